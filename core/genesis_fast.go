@@ -0,0 +1,112 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// genesis_fast.go mirrors go-ethereum's genesis setup/compatibility-check
+// dance for the Fast chain: SetupGenesisBlock_Fast writes the genesis and
+// config on a pristine database, detects a genesis mismatch, and on a
+// config change that's incompatible with blocks already stored, either
+// rewinds the Fast chain's head pointers to the last block below the
+// incompatible fork height or refuses to start if that block is gone.
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/core/rawdb"
+	"github.com/truechain/truechain-engineering-code/log"
+	"github.com/truechain/truechain-engineering-code/params"
+)
+
+// errGenesisNoConfigFast is returned by SetupGenesisBlock_Fast when called
+// without a chain config to apply.
+var errGenesisNoConfigFast = errors.New("fast chain genesis has no chain config")
+
+// GenesisMismatchErrorFast is returned when the Fast chain genesis hash
+// stored in the database doesn't match the one the caller supplied.
+type GenesisMismatchErrorFast struct {
+	Stored, New common.Hash
+}
+
+func (e *GenesisMismatchErrorFast) Error() string {
+	return fmt.Sprintf("fast chain database contains incompatible genesis (have %x, new %x)", e.Stored, e.New)
+}
+
+// SetupGenesisBlock_Fast writes the Fast chain's genesis hash and chain
+// config into db if it's pristine, otherwise checks the stored genesis and
+// config against the ones supplied. A config change that's incompatible
+// with the Fast chain's current head is resolved by rewinding the head
+// pointers to the last block below the incompatible fork height; if that
+// block's canonical hash is not known the
+// mismatch is returned as an error instead, so the caller can refuse to
+// start rather than run with an inconsistent chain.
+//
+// The returned chain config is the one that should be used from now on; it
+// is nil only when err is non-nil.
+func SetupGenesisBlock_Fast(db rawdb.DatabaseReadWriter, genesisHash common.Hash, genesisCfg *params.ChainConfig) (*params.ChainConfig, common.Hash, error) {
+	if genesisCfg == nil {
+		return nil, common.Hash{}, errGenesisNoConfigFast
+	}
+
+	stored := rawdb.ReadGenesisBlockHash_Fast(db)
+	if stored == (common.Hash{}) {
+		log.Info("Writing fast chain genesis block", "hash", genesisHash)
+		rawdb.WriteGenesisBlockHash_Fast(db, genesisHash)
+		rawdb.WriteChainConfig_Fast(db, genesisHash, genesisCfg)
+		return genesisCfg, genesisHash, nil
+	}
+	if stored != genesisHash {
+		return genesisCfg, stored, &GenesisMismatchErrorFast{Stored: stored, New: genesisHash}
+	}
+
+	storedCfg := rawdb.ReadChainConfig_Fast(db, stored)
+	if storedCfg == nil {
+		log.Warn("Found fast chain genesis block without chain config")
+		rawdb.WriteChainConfig_Fast(db, stored, genesisCfg)
+		return genesisCfg, stored, nil
+	}
+
+	headNumber := rawdb.ReadHeaderNumber_Fast(db, rawdb.ReadHeadHeaderHash_Fast(db))
+	if headNumber == nil {
+		rawdb.WriteChainConfig_Fast(db, stored, genesisCfg)
+		return genesisCfg, stored, nil
+	}
+
+	if compatErr := storedCfg.CheckCompatible(genesisCfg, *headNumber); compatErr != nil {
+		if err := rewindFastChain(db, compatErr.RewindTo); err != nil {
+			return storedCfg, stored, fmt.Errorf("fast chain config incompatible (%v) and cannot rewind: %v", compatErr, err)
+		}
+		log.Warn("Rewound fast chain to pre-fork head", "reason", compatErr, "number", compatErr.RewindTo)
+	}
+
+	rawdb.WriteChainConfig_Fast(db, stored, genesisCfg)
+	return genesisCfg, stored, nil
+}
+
+// rewindFastChain resets the Fast chain's head header/block/fast-block
+// pointers to the canonical block at rewindTo. It fails if that block's
+// canonical hash is not known in either the key-value or the ancient store.
+func rewindFastChain(db rawdb.DatabaseReadWriter, rewindTo uint64) error {
+	hash := rawdb.ReadCanonicalHash_Fast(db, rewindTo)
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("no canonical fast block at height %d to rewind to", rewindTo)
+	}
+	rawdb.WriteHeadHeaderHash_Fast(db, hash)
+	rawdb.WriteHeadBlockHash_Fast(db, hash)
+	rawdb.WriteHeadFastBlockHash_Fast(db, hash)
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/core/rawdb"
+	"github.com/truechain/truechain-engineering-code/ethdb"
+	"github.com/truechain/truechain-engineering-code/params"
+)
+
+// setupIncompatibleFastChain writes a genesis plus a dense run of canonical
+// blocks 0..headNumber under oldCfg, so a later SetupGenesisBlock_Fast call
+// with newCfg has a head to detect an incompatible fork change against.
+func setupIncompatibleFastChain(t *testing.T, headNumber uint64, prune bool) (ethdb.Database, common.Hash, *params.ChainConfig) {
+	db := ethdb.NewMemDatabase()
+	genesisHash := common.BytesToHash([]byte("fast genesis"))
+	oldCfg := &params.ChainConfig{ChainId: big.NewInt(1), HomesteadBlock: big.NewInt(0)}
+
+	if _, _, err := SetupGenesisBlock_Fast(db, genesisHash, oldCfg); err != nil {
+		t.Fatalf("unexpected error writing pristine genesis: %v", err)
+	}
+
+	for number := uint64(0); number <= headNumber; number++ {
+		if prune && number == 0 {
+			// Leave the earliest block's canonical hash unknown.
+			continue
+		}
+		// The leading 1 keeps block 0's hash from being the zero hash, which
+		// ReadCanonicalHash_Fast reports for a missing block.
+		hash := common.BytesToHash([]byte{1, byte(number)})
+		rawdb.WriteCanonicalHash_Fast(db, hash, number)
+		if number == headNumber {
+			rawdb.WriteHeaderNumber_Fast(db, hash, number)
+			rawdb.WriteHeadHeaderHash_Fast(db, hash)
+		}
+	}
+	return db, genesisHash, oldCfg
+}
+
+// Tests that SetupGenesisBlock_Fast writes the genesis hash and chain
+// config on a pristine database, and returns them unchanged on a later
+// call with the same genesis.
+func TestSetupGenesisBlockFastFresh(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	genesisHash := common.BytesToHash([]byte("fast genesis"))
+	cfg := &params.ChainConfig{ChainId: big.NewInt(1), HomesteadBlock: big.NewInt(0)}
+
+	got, hash, err := SetupGenesisBlock_Fast(db, genesisHash, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hash != genesisHash || got != cfg {
+		t.Fatalf("mismatch: have (%v, %x), want (%v, %x)", got, hash, cfg, genesisHash)
+	}
+	if stored := rawdb.ReadGenesisBlockHash_Fast(db); stored != genesisHash {
+		t.Fatalf("genesis hash not persisted: have %x, want %x", stored, genesisHash)
+	}
+
+	got, hash, err = SetupGenesisBlock_Fast(db, genesisHash, cfg)
+	if err != nil || hash != genesisHash {
+		t.Fatalf("second call failed: (%v, %x, %v)", got, hash, err)
+	}
+}
+
+// Tests that SetupGenesisBlock_Fast refuses to start when an incompatible
+// config change would require rewinding to a block whose canonical hash is
+// not known.
+func TestSetupGenesisBlockFastRefusesWhenRewindUnavailable(t *testing.T) {
+	db, genesisHash, _ := setupIncompatibleFastChain(t, 5, true)
+	newCfg := &params.ChainConfig{ChainId: big.NewInt(1), HomesteadBlock: big.NewInt(3)}
+
+	if _, _, err := SetupGenesisBlock_Fast(db, genesisHash, newCfg); err == nil {
+		t.Fatal("expected an error refusing to start on unrewindable incompatible config")
+	}
+}
+
+// Tests that SetupGenesisBlock_Fast automatically rewinds the Fast chain's
+// head pointers to the last block below an incompatible fork height when
+// that block is still available.
+func TestSetupGenesisBlockFastAutoRewind(t *testing.T) {
+	db, genesisHash, _ := setupIncompatibleFastChain(t, 5, false)
+	newCfg := &params.ChainConfig{ChainId: big.NewInt(1), HomesteadBlock: big.NewInt(3)}
+
+	if _, _, err := SetupGenesisBlock_Fast(db, genesisHash, newCfg); err != nil {
+		t.Fatalf("expected automatic rewind, got error: %v", err)
+	}
+
+	headHash := rawdb.ReadHeadHeaderHash_Fast(db)
+	if headHash == common.BytesToHash([]byte{1, 5}) {
+		t.Fatal("head header hash was not rewound off the post-fork head")
+	}
+	if got := rawdb.ReadHeadBlockHash_Fast(db); got != headHash {
+		t.Fatalf("head block hash not rewound in lockstep: have %x, want %x", got, headHash)
+	}
+	if got := rawdb.ReadHeadFastBlockHash_Fast(db); got != headHash {
+		t.Fatalf("head fast block hash not rewound in lockstep: have %x, want %x", got, headHash)
+	}
+}
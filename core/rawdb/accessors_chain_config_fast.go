@@ -0,0 +1,76 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// accessors_chain_config_fast.go persists the Fast chain's genesis hash and
+// the params.ChainConfig it was configured with, so core can detect a
+// config change that's incompatible with blocks already on disk.
+package rawdb
+
+import (
+	"encoding/json"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/log"
+	"github.com/truechain/truechain-engineering-code/params"
+)
+
+// WriteGenesisBlockHash_Fast stores the Fast chain's genesis block hash.
+func WriteGenesisBlockHash_Fast(db DatabaseWriter, hash common.Hash) {
+	if err := db.Put(genesisHashKeyFast, hash.Bytes()); err != nil {
+		log.Crit("Failed to store fast chain genesis hash", "err", err)
+	}
+}
+
+// ReadGenesisBlockHash_Fast retrieves the Fast chain's genesis block hash.
+// It returns an empty hash if none has been stored yet.
+func ReadGenesisBlockHash_Fast(db DatabaseReader) common.Hash {
+	data, _ := db.Get(genesisHashKeyFast)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteChainConfig_Fast stores the Fast chain config under the given
+// genesis hash, so a later start can detect whether the config has changed
+// in a way that's incompatible with blocks already stored under it.
+func WriteChainConfig_Fast(db DatabaseWriter, hash common.Hash, cfg *params.ChainConfig) {
+	if cfg == nil {
+		return
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		log.Crit("Failed to JSON encode fast chain config", "err", err)
+	}
+	if err := db.Put(configKeyFast(hash), data); err != nil {
+		log.Crit("Failed to store fast chain config", "err", err)
+	}
+}
+
+// ReadChainConfig_Fast retrieves the Fast chain config stored under the
+// given genesis hash. It returns nil if no config has been stored for it.
+func ReadChainConfig_Fast(db DatabaseReader, hash common.Hash) *params.ChainConfig {
+	data, _ := db.Get(configKeyFast(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	cfg := new(params.ChainConfig)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		log.Error("Invalid fast chain config JSON", "hash", hash, "err", err)
+		return nil
+	}
+	return cfg
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/ethdb"
+	"github.com/truechain/truechain-engineering-code/params"
+)
+
+// Tests that a Fast chain config can be written and read back unchanged,
+// and that the genesis hash it's stored under round-trips as well.
+func TestChainConfigStorage_Fast(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	hash := common.BytesToHash([]byte("genesis"))
+
+	if cfg := ReadChainConfig_Fast(db, hash); cfg != nil {
+		t.Fatalf("non existent config returned: %v", cfg)
+	}
+	if got := ReadGenesisBlockHash_Fast(db); got != (common.Hash{}) {
+		t.Fatalf("non existent genesis hash returned: %x", got)
+	}
+
+	WriteGenesisBlockHash_Fast(db, hash)
+	if got := ReadGenesisBlockHash_Fast(db); got != hash {
+		t.Fatalf("genesis hash mismatch: have %x, want %x", got, hash)
+	}
+
+	cfg := &params.ChainConfig{ChainId: big.NewInt(1), HomesteadBlock: big.NewInt(0)}
+	WriteChainConfig_Fast(db, hash, cfg)
+	if got := ReadChainConfig_Fast(db, hash); !reflect.DeepEqual(got, cfg) {
+		t.Fatalf("config mismatch: have %v, want %v", got, cfg)
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// accessors_chain_fast.go mirrors accessors_chain.go's header/body/block/td
+// accessors for the Fast chain's own key space (see schema_fast.go), so the
+// two chains never share a key and can be pruned, frozen, or rewound
+// independently of one another.
+package rawdb
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/core/types"
+	"github.com/truechain/truechain-engineering-code/ethdb"
+	"github.com/truechain/truechain-engineering-code/log"
+	"github.com/truechain/truechain-engineering-code/rlp"
+)
+
+// ancientFast returns db's ethdb.AncientReader, if it has one. Plain
+// key-value databases (like the one ethdb.NewMemDatabase returns) don't
+// implement it, so every Read*_Fast accessor below falls back to a KV-only
+// lookup against those.
+func ancientFast(db DatabaseReader) (ethdb.AncientReader, bool) {
+	ancient, ok := db.(ethdb.AncientReader)
+	return ancient, ok
+}
+
+// ReadCanonicalHash retrieves the hash assigned to a canonical block number.
+func ReadCanonicalHash_Fast(db DatabaseReader, number uint64) common.Hash {
+	data, _ := db.Get(headerHashKeyFast(number))
+	if len(data) == 0 {
+		if ancient, ok := ancientFast(db); ok {
+			data, _ = ancient.Ancient(freezerHashTableFast, number)
+		}
+	}
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteCanonicalHash stores the hash assigned to a canonical block number.
+func WriteCanonicalHash_Fast(db DatabaseWriter, hash common.Hash, number uint64) {
+	if err := db.Put(headerHashKeyFast(number), hash.Bytes()); err != nil {
+		log.Crit("Failed to store fast chain number to hash mapping", "err", err)
+	}
+}
+
+// DeleteCanonicalHash removes the number to hash canonical mapping.
+func DeleteCanonicalHash_Fast(db DatabaseDeleter, number uint64) {
+	if err := db.Delete(headerHashKeyFast(number)); err != nil {
+		log.Crit("Failed to delete fast chain number to hash mapping", "err", err)
+	}
+}
+
+// ReadHeaderNumber returns the header number assigned to a hash.
+func ReadHeaderNumber_Fast(db DatabaseReader, hash common.Hash) *uint64 {
+	data, _ := db.Get(headerNumberKeyFast(hash))
+	if len(data) != 8 {
+		return nil
+	}
+	number := decodeBlockNumberFast(data)
+	return &number
+}
+
+func decodeBlockNumberFast(enc []byte) uint64 {
+	var number uint64
+	for _, b := range enc {
+		number = number<<8 | uint64(b)
+	}
+	return number
+}
+
+// ReadHeadHeaderHash retrieves the hash of the current canonical head header.
+func ReadHeadHeaderHash_Fast(db DatabaseReader) common.Hash {
+	data, _ := db.Get(headHeaderKeyFast)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteHeadHeaderHash stores the hash of the current canonical head header.
+func WriteHeadHeaderHash_Fast(db DatabaseWriter, hash common.Hash) {
+	if err := db.Put(headHeaderKeyFast, hash.Bytes()); err != nil {
+		log.Crit("Failed to store fast chain last header's hash", "err", err)
+	}
+}
+
+// ReadHeadBlockHash retrieves the hash of the current canonical head block.
+func ReadHeadBlockHash_Fast(db DatabaseReader) common.Hash {
+	data, _ := db.Get(headBlockKeyFast)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteHeadBlockHash stores the hash of the current canonical head block.
+func WriteHeadBlockHash_Fast(db DatabaseWriter, hash common.Hash) {
+	if err := db.Put(headBlockKeyFast, hash.Bytes()); err != nil {
+		log.Crit("Failed to store fast chain last block's hash", "err", err)
+	}
+}
+
+// ReadHeadFastBlockHash retrieves the hash of the current fast-sync pivot block.
+func ReadHeadFastBlockHash_Fast(db DatabaseReader) common.Hash {
+	data, _ := db.Get(headFastBlockKeyFast)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteHeadFastBlockHash stores the hash of the current fast-sync pivot block.
+func WriteHeadFastBlockHash_Fast(db DatabaseWriter, hash common.Hash) {
+	if err := db.Put(headFastBlockKeyFast, hash.Bytes()); err != nil {
+		log.Crit("Failed to store fast chain last fast block's hash", "err", err)
+	}
+}
+
+// ReadHeaderRLP retrieves a block header in its raw RLP database encoding,
+// checking the ancient store first since that is where old headers end up
+// once freezeOnce has migrated them out of the key-value store.
+func ReadHeaderRLP_Fast(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	if ancient, ok := ancientFast(db); ok {
+		if data, _ := ancient.Ancient(freezerHeaderTableFast, number); len(data) > 0 {
+			return data
+		}
+	}
+	data, _ := db.Get(headerKeyFast(number, hash))
+	return data
+}
+
+// HasHeader verifies the existence of a block header corresponding to the hash.
+func HasHeader_Fast(db DatabaseReader, hash common.Hash, number uint64) bool {
+	if has, err := db.Has(headerKeyFast(number, hash)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadHeader retrieves the block header corresponding to the hash.
+func ReadHeader_Fast(db DatabaseReader, hash common.Hash, number uint64) *types.FastHeader {
+	data := ReadHeaderRLP_Fast(db, hash, number)
+	if len(data) == 0 {
+		return nil
+	}
+	header := new(types.FastHeader)
+	if err := rlp.Decode(bytes.NewReader(data), header); err != nil {
+		log.Error("Invalid fast chain header RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return header
+}
+
+// WriteHeader stores a block header into the database and also stores the
+// hash-to-number mapping.
+func WriteHeader_Fast(db DatabaseWriter, header *types.FastHeader) {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+	)
+	WriteHeaderNumber_Fast(db, hash, number)
+
+	data, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		log.Crit("Failed to RLP encode fast chain header", "err", err)
+	}
+	if err := db.Put(headerKeyFast(number, hash), data); err != nil {
+		log.Crit("Failed to store fast chain header", "err", err)
+	}
+}
+
+// WriteHeaderNumber stores the hash-to-number mapping a header needs before
+// it can be looked up by hash alone.
+func WriteHeaderNumber_Fast(db DatabaseWriter, hash common.Hash, number uint64) {
+	if err := db.Put(headerNumberKeyFast(hash), encodeBlockNumberFast(number)); err != nil {
+		log.Crit("Failed to store fast chain hash to number mapping", "err", err)
+	}
+}
+
+// DeleteHeader removes a block header and its hash-to-number mapping.
+func DeleteHeader_Fast(db DatabaseDeleter, hash common.Hash, number uint64) {
+	if err := db.Delete(headerKeyFast(number, hash)); err != nil {
+		log.Crit("Failed to delete fast chain header", "err", err)
+	}
+	if err := db.Delete(headerNumberKeyFast(hash)); err != nil {
+		log.Crit("Failed to delete fast chain hash to number mapping", "err", err)
+	}
+}
+
+// ReadBodyRLP retrieves the block body (transactions) in RLP encoding,
+// checking the ancient store first (see ReadHeaderRLP_Fast).
+func ReadBodyRLP_Fast(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	if ancient, ok := ancientFast(db); ok {
+		if data, _ := ancient.Ancient(freezerBodiesTableFast, number); len(data) > 0 {
+			return data
+		}
+	}
+	data, _ := db.Get(blockBodyKeyFast(number, hash))
+	return data
+}
+
+// WriteBodyRLP stores an RLP encoded block body into the database.
+func WriteBodyRLP_Fast(db DatabaseWriter, hash common.Hash, number uint64, rlp rlp.RawValue) {
+	if err := db.Put(blockBodyKeyFast(number, hash), rlp); err != nil {
+		log.Crit("Failed to store fast chain block body", "err", err)
+	}
+}
+
+// HasBody verifies the existence of a block body corresponding to the hash.
+func HasBody_Fast(db DatabaseReader, hash common.Hash, number uint64) bool {
+	if has, err := db.Has(blockBodyKeyFast(number, hash)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadBody retrieves the block body corresponding to the hash.
+func ReadBody_Fast(db DatabaseReader, hash common.Hash, number uint64) *types.FastBody {
+	data := ReadBodyRLP_Fast(db, hash, number)
+	if len(data) == 0 {
+		return nil
+	}
+	body := new(types.FastBody)
+	if err := rlp.Decode(bytes.NewReader(data), body); err != nil {
+		log.Error("Invalid fast chain body RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return body
+}
+
+// WriteBody stores a block body into the database.
+func WriteBody_Fast(db DatabaseWriter, hash common.Hash, number uint64, body *types.FastBody) {
+	data, err := rlp.EncodeToBytes(body)
+	if err != nil {
+		log.Crit("Failed to RLP encode fast chain body", "err", err)
+	}
+	WriteBodyRLP_Fast(db, hash, number, data)
+}
+
+// DeleteBody removes a block body from the database.
+func DeleteBody_Fast(db DatabaseDeleter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockBodyKeyFast(number, hash)); err != nil {
+		log.Crit("Failed to delete fast chain body", "err", err)
+	}
+}
+
+// ReadTd retrieves the total difficulty corresponding to a block hash,
+// checking the key-value store first and falling back to the ancient store.
+func ReadTd_Fast(db DatabaseReader, hash common.Hash, number uint64) *big.Int {
+	data, _ := db.Get(headerTDKeyFast(number, hash))
+	if len(data) == 0 {
+		if ancient, ok := ancientFast(db); ok {
+			data, _ = ancient.Ancient(freezerDifficultyTableFast, number)
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	td := new(big.Int)
+	if err := rlp.Decode(bytes.NewReader(data), td); err != nil {
+		log.Error("Invalid fast chain total difficulty RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return td
+}
+
+// WriteTd stores the total difficulty of a block into the database.
+func WriteTd_Fast(db DatabaseWriter, hash common.Hash, number uint64, td *big.Int) {
+	data, err := rlp.EncodeToBytes(td)
+	if err != nil {
+		log.Crit("Failed to RLP encode fast chain total difficulty", "err", err)
+	}
+	if err := db.Put(headerTDKeyFast(number, hash), data); err != nil {
+		log.Crit("Failed to store fast chain total difficulty", "err", err)
+	}
+}
+
+// DeleteTd removes the total difficulty of a block from the database.
+func DeleteTd_Fast(db DatabaseDeleter, hash common.Hash, number uint64) {
+	if err := db.Delete(headerTDKeyFast(number, hash)); err != nil {
+		log.Crit("Failed to delete fast chain total difficulty", "err", err)
+	}
+}
+
+// ReadReceipts retrieves all the transaction receipts belonging to a block,
+// checking the key-value store first and falling back to the ancient store,
+// and rehydrates
+// every field the compact on-disk format (ReceiptForStorageFast) doesn't
+// carry by walking the block's transactions.
+func ReadReceipts_Fast(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
+	data, _ := db.Get(blockReceiptsKeyFast(number, hash))
+	if len(data) == 0 {
+		if ancient, ok := ancientFast(db); ok {
+			data, _ = ancient.Ancient(freezerReceiptTableFast, number)
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var storage []*ReceiptForStorageFast
+	if err := rlp.Decode(bytes.NewReader(data), &storage); err == nil {
+		receipts := make(types.Receipts, len(storage))
+		for i, r := range storage {
+			receipts[i] = (*types.Receipt)(r)
+		}
+		if body := ReadBody_Fast(db, hash, number); body != nil {
+			deriveReceiptFieldsFast(receipts, hash, number, body.Transactions)
+		}
+		return receipts
+	}
+
+	// Fall back to the legacy full-receipt encoding written before
+	// ReceiptForStorageFast existed.
+	var receipts types.Receipts
+	if err := rlp.Decode(bytes.NewReader(data), &receipts); err != nil {
+		log.Error("Invalid fast chain receipt array RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return receipts
+}
+
+// WriteReceipts stores all the transaction receipts belonging to a block,
+// using the compact ReceiptForStorageFast encoding.
+func WriteReceipts_Fast(db DatabaseWriter, hash common.Hash, number uint64, receipts types.Receipts) {
+	storage := make([]*ReceiptForStorageFast, len(receipts))
+	for i, r := range receipts {
+		storage[i] = (*ReceiptForStorageFast)(r)
+	}
+	data, err := rlp.EncodeToBytes(storage)
+	if err != nil {
+		log.Crit("Failed to RLP encode fast chain receipts", "err", err)
+	}
+	if err := db.Put(blockReceiptsKeyFast(number, hash), data); err != nil {
+		log.Crit("Failed to store fast chain receipts", "err", err)
+	}
+}
+
+// DeleteReceipts removes all the transaction receipts belonging to a block.
+func DeleteReceipts_Fast(db DatabaseDeleter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockReceiptsKeyFast(number, hash)); err != nil {
+		log.Crit("Failed to delete fast chain receipts", "err", err)
+	}
+}
+
+// ReadBlock retrieves an entire block corresponding to the hash, assembling
+// it back from the stored header and body.
+func ReadBlock_Fast(db DatabaseReader, hash common.Hash, number uint64) *types.FastBlock {
+	header := ReadHeader_Fast(db, hash, number)
+	if header == nil {
+		return nil
+	}
+	body := ReadBody_Fast(db, hash, number)
+	if body == nil {
+		return nil
+	}
+	return types.NewFastBlockWithHeader(header).WithBody(body.Transactions)
+}
+
+// WriteBlock serializes a block into the database, header and body separately.
+func WriteBlock_Fast(db DatabaseWriter, block *types.FastBlock) {
+	WriteBody_Fast(db, block.Hash(), block.NumberU64(), block.Body())
+	WriteHeader_Fast(db, block.Header())
+	WriteTxLookupEntries_Fast(db, block)
+}
+
+// DeleteBlock removes all block data associated with a hash.
+func DeleteBlock_Fast(db DatabaseDeleteReader, hash common.Hash, number uint64) {
+	if body := ReadBody_Fast(db, hash, number); body != nil {
+		deleteTxLookupEntriesForTransactions_Fast(db, body.Transactions)
+	}
+	DeleteReceipts_Fast(db, hash, number)
+	DeleteHeader_Fast(db, hash, number)
+	DeleteBody_Fast(db, hash, number)
+	DeleteTd_Fast(db, hash, number)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/truechain/truechain-engineering-code/common"
 	"github.com/truechain/truechain-engineering-code/core/types"
+	"github.com/truechain/truechain-engineering-code/crypto"
 	"github.com/truechain/truechain-engineering-code/crypto/sha3"
 	"github.com/truechain/truechain-engineering-code/ethdb"
 	"github.com/truechain/truechain-engineering-code/rlp"
@@ -282,6 +283,7 @@ func TestBlockReceiptStorage_Fast(t *testing.T) {
 		ContractAddress: common.BytesToAddress([]byte{0x01, 0x11, 0x11}),
 		GasUsed:         111111,
 	}
+	receipt1.Bloom = types.CreateBloom(types.Receipts{receipt1})
 	receipt2 := &types.Receipt{
 		PostState:         common.Hash{2}.Bytes(),
 		CumulativeGasUsed: 2,
@@ -293,6 +295,7 @@ func TestBlockReceiptStorage_Fast(t *testing.T) {
 		ContractAddress: common.BytesToAddress([]byte{0x02, 0x22, 0x22}),
 		GasUsed:         222222,
 	}
+	receipt2.Bloom = types.CreateBloom(types.Receipts{receipt2})
 	receipts := []*types.Receipt{receipt1, receipt2}
 
 	// Check that no receipt entries are in a pristine database
@@ -319,4 +322,112 @@ func TestBlockReceiptStorage_Fast(t *testing.T) {
 	if rs := ReadReceipts_Fast(db, hash, 0); len(rs) != 0 {
 		t.Fatalf("deleted receipts returned: %v", rs)
 	}
+}
+
+// Tests that the compact ReceiptForStorageFast encoding round-trips every
+// field ReadReceipts_Fast rehydrates from the owning block: TxHash, GasUsed,
+// ContractAddress and each log's block/tx positional metadata.
+func TestReceiptStorageFastRehydration(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	creation, err := types.SignTx(types.NewContractCreation(0, big.NewInt(0), 100000, big.NewInt(1), []byte{0x60, 0x00}), signer, key)
+	if err != nil {
+		t.Fatalf("sign creation tx: %v", err)
+	}
+	call, err := types.SignTx(types.NewTransaction(1, common.BytesToAddress([]byte{0x01}), big.NewInt(0), 50000, big.NewInt(1), nil), signer, key)
+	if err != nil {
+		t.Fatalf("sign call tx: %v", err)
+	}
+	txs := []*types.Transaction{creation, call}
+
+	from, err := types.Sender(signer, creation)
+	if err != nil {
+		t.Fatalf("recover sender: %v", err)
+	}
+	wantContractAddress := crypto.CreateAddress(from, creation.Nonce())
+
+	receipt1 := &types.Receipt{CumulativeGasUsed: 21000, Logs: []*types.Log{{Address: wantContractAddress}}}
+	receipt2 := &types.Receipt{CumulativeGasUsed: 42000, Logs: []*types.Log{{Address: common.BytesToAddress([]byte{0x01})}}}
+	receipts := types.Receipts{receipt1, receipt2}
+
+	block := types.NewFastBlockWithHeader(&types.FastHeader{Number: big.NewInt(7)}).WithBody(txs)
+
+	WriteBody_Fast(db, block.Hash(), block.NumberU64(), block.Body())
+	WriteReceipts_Fast(db, block.Hash(), block.NumberU64(), receipts)
+
+	got := ReadReceipts_Fast(db, block.Hash(), block.NumberU64())
+	if len(got) != 2 {
+		t.Fatalf("receipt count: have %d, want 2", len(got))
+	}
+	if got[0].TxHash != creation.Hash() {
+		t.Fatalf("receipt 0 TxHash: have %x, want %x", got[0].TxHash, creation.Hash())
+	}
+	if got[0].GasUsed != 21000 {
+		t.Fatalf("receipt 0 GasUsed: have %d, want 21000", got[0].GasUsed)
+	}
+	if got[0].ContractAddress != wantContractAddress {
+		t.Fatalf("receipt 0 ContractAddress: have %x, want %x", got[0].ContractAddress, wantContractAddress)
+	}
+	if got[1].TxHash != call.Hash() {
+		t.Fatalf("receipt 1 TxHash: have %x, want %x", got[1].TxHash, call.Hash())
+	}
+	if got[1].GasUsed != 21000 {
+		t.Fatalf("receipt 1 GasUsed: have %d, want 21000 (42000-21000)", got[1].GasUsed)
+	}
+	for i, r := range got {
+		for j, l := range r.Logs {
+			if l.BlockHash != block.Hash() || l.BlockNumber != block.NumberU64() || l.TxIndex != uint(i) {
+				t.Fatalf("receipt %d log %d: positional metadata not rehydrated: %+v", i, j, l)
+			}
+		}
+	}
+}
+
+// Tests that transactions can be stored and retrieved via their lookup
+// entries (hash -> blockHash/blockNumber/index).
+func TestLookupStorage_Fast(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	tx1 := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11})
+	tx2 := types.NewTransaction(2, common.BytesToAddress([]byte{0x22}), big.NewInt(222), 2222, big.NewInt(22222), []byte{0x22, 0x22, 0x22})
+	tx3 := types.NewTransaction(3, common.BytesToAddress([]byte{0x33}), big.NewInt(333), 3333, big.NewInt(33333), []byte{0x33, 0x33, 0x33})
+	txs := []*types.Transaction{tx1, tx2, tx3}
+
+	block := types.NewFastBlockWithHeader(&types.FastHeader{Number: big.NewInt(314)}).WithBody(txs)
+
+	// Check that no transactions entries are in a pristine database
+	for i, tx := range txs {
+		if txn, _, _, _ := ReadTransaction_Fast(db, tx.Hash()); txn != nil {
+			t.Fatalf("tx #%d [%x]: non existent transaction returned: %v", i, tx.Hash(), txn)
+		}
+	}
+	// Insert all the transactions into the database, and verify contents
+	WriteBody_Fast(db, block.Hash(), block.NumberU64(), block.Body())
+	WriteTxLookupEntries_Fast(db, block)
+
+	for i, tx := range txs {
+		if txn, hash, number, index := ReadTransaction_Fast(db, tx.Hash()); txn == nil {
+			t.Fatalf("tx #%d [%x]: transaction not found", i, tx.Hash())
+		} else {
+			if hash != block.Hash() || number != block.NumberU64() || index != uint64(i) {
+				t.Fatalf("tx #%d [%x]: positional metadata mismatch: have %x/%d/%d, want %x/%v/%v", i, tx.Hash(), hash, number, index, block.Hash(), block.NumberU64(), i)
+			}
+			if tx.Hash() != txn.Hash() {
+				t.Fatalf("tx #%d [%x]: transaction mismatch: have %v, want %v", i, tx.Hash(), txn, tx)
+			}
+		}
+	}
+	// Delete the transactions and check purge
+	for i, tx := range txs {
+		DeleteTxLookupEntry_Fast(db, tx.Hash())
+		if txn, _, _, _ := ReadTransaction_Fast(db, tx.Hash()); txn != nil {
+			t.Fatalf("tx #%d [%x]: deleted transaction returned: %v", i, tx.Hash(), txn)
+		}
+	}
 }
\ No newline at end of file
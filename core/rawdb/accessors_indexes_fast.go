@@ -0,0 +1,120 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// accessors_indexes_fast.go is the Fast chain's equivalent of go-ethereum's
+// accessors_indexes.go: it maps a transaction hash to the (block, index)
+// it was included at, so a transaction or receipt can be looked up directly
+// instead of scanning every block.
+package rawdb
+
+import (
+	"bytes"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/core/types"
+	"github.com/truechain/truechain-engineering-code/log"
+	"github.com/truechain/truechain-engineering-code/rlp"
+)
+
+// TxLookupEntryFast is the RLP-encoded value stored for every transaction's
+// lookup key: which block it's in, and at what index within that block.
+type TxLookupEntryFast struct {
+	BlockHash  common.Hash
+	BlockIndex uint64
+	Index      uint64
+}
+
+// WriteTxLookupEntries stores a lookup entry for every transaction in block,
+// so ReadTransaction_Fast/ReadReceipt_Fast can resolve it without scanning.
+func WriteTxLookupEntries_Fast(db DatabaseWriter, block *types.FastBlock) {
+	for i, tx := range block.Transactions() {
+		entry := TxLookupEntryFast{
+			BlockHash:  block.Hash(),
+			BlockIndex: block.NumberU64(),
+			Index:      uint64(i),
+		}
+		data, err := rlp.EncodeToBytes(entry)
+		if err != nil {
+			log.Crit("Failed to RLP encode fast chain tx lookup entry", "err", err)
+		}
+		if err := db.Put(txLookupKeyFast(tx.Hash()), data); err != nil {
+			log.Crit("Failed to store fast chain tx lookup entry", "err", err)
+		}
+	}
+}
+
+// ReadTxLookupEntry retrieves the (blockHash, blockNumber, txIndex) tuple a
+// transaction hash was last indexed under. It returns a zero common.Hash if
+// no entry is present.
+func ReadTxLookupEntry_Fast(db DatabaseReader, txHash common.Hash) (common.Hash, uint64, uint64) {
+	data, _ := db.Get(txLookupKeyFast(txHash))
+	if len(data) == 0 {
+		return common.Hash{}, 0, 0
+	}
+	var entry TxLookupEntryFast
+	if err := rlp.Decode(bytes.NewReader(data), &entry); err != nil {
+		log.Error("Invalid fast chain tx lookup entry RLP", "hash", txHash, "err", err)
+		return common.Hash{}, 0, 0
+	}
+	return entry.BlockHash, entry.BlockIndex, entry.Index
+}
+
+// DeleteTxLookupEntry removes a single transaction's lookup entry.
+func DeleteTxLookupEntry_Fast(db DatabaseDeleter, txHash common.Hash) {
+	if err := db.Delete(txLookupKeyFast(txHash)); err != nil {
+		log.Crit("Failed to delete fast chain tx lookup entry", "err", err)
+	}
+}
+
+// deleteTxLookupEntriesForTransactions_Fast removes the lookup entry for
+// every transaction in txs; used by DeleteBlock_Fast, which only has the
+// block's body (and so its transactions) available before the body itself
+// is deleted.
+func deleteTxLookupEntriesForTransactions_Fast(db DatabaseDeleter, txs types.Transactions) {
+	for _, tx := range txs {
+		DeleteTxLookupEntry_Fast(db, tx.Hash())
+	}
+}
+
+// ReadTransaction retrieves a specific transaction by hash, along with the
+// block hash, block number and index within that block it was found at.
+func ReadTransaction_Fast(db DatabaseReader, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
+	blockHash, blockNumber, txIndex := ReadTxLookupEntry_Fast(db, txHash)
+	if blockHash == (common.Hash{}) {
+		return nil, common.Hash{}, 0, 0
+	}
+	body := ReadBody_Fast(db, blockHash, blockNumber)
+	if body == nil || len(body.Transactions) <= int(txIndex) {
+		log.Error("Fast chain tx referenced missing transaction", "hash", txHash, "block", blockNumber, "index", txIndex)
+		return nil, common.Hash{}, 0, 0
+	}
+	return body.Transactions[txIndex], blockHash, blockNumber, txIndex
+}
+
+// ReadReceipt retrieves a specific transaction receipt by hash, along with
+// the block hash, block number and index it was found at.
+func ReadReceipt_Fast(db DatabaseReader, txHash common.Hash) (*types.Receipt, common.Hash, uint64, uint64) {
+	blockHash, blockNumber, receiptIndex := ReadTxLookupEntry_Fast(db, txHash)
+	if blockHash == (common.Hash{}) {
+		return nil, common.Hash{}, 0, 0
+	}
+	receipts := ReadReceipts_Fast(db, blockHash, blockNumber)
+	if len(receipts) <= int(receiptIndex) {
+		log.Error("Fast chain tx referenced missing receipt", "hash", txHash, "block", blockNumber, "index", receiptIndex)
+		return nil, common.Hash{}, 0, 0
+	}
+	return receipts[receiptIndex], blockHash, blockNumber, receiptIndex
+}
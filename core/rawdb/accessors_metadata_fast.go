@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// accessors_metadata_fast.go holds accessors for the Fast chain's own
+// database schema version, plus the one-off migration it gates.
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/log"
+)
+
+// txLookupSchemaVersionFast is the schema version at which every block's
+// transactions gained a lookup entry (see accessors_indexes_fast.go). A
+// database opened below this version needs migrateTxLookupEntries_Fast run
+// against it before lookups can be trusted.
+const txLookupSchemaVersionFast uint64 = 1
+
+// ReadDatabaseVersion retrieves the Fast chain's database schema version.
+// It returns nil for a pristine database that has never recorded one.
+func ReadDatabaseVersion_Fast(db DatabaseReader) *uint64 {
+	data, _ := db.Get(databaseVersionKeyFast)
+	if len(data) != 8 {
+		return nil
+	}
+	version := binary.BigEndian.Uint64(data)
+	return &version
+}
+
+// WriteDatabaseVersion stores the Fast chain's database schema version.
+func WriteDatabaseVersion_Fast(db DatabaseWriter, version uint64) {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], version)
+	if err := db.Put(databaseVersionKeyFast, enc[:]); err != nil {
+		log.Crit("Failed to store fast chain database version", "err", err)
+	}
+}
+
+// MigrateTxLookupEntries brings a database that pre-dates
+// txLookupSchemaVersionFast up to date by re-deriving every block's tx
+// lookup entries from its stored body, then records the new version so
+// this only ever runs once. headNumber should be the current canonical
+// head; the scan stops as soon as a canonical block is missing.
+//
+// Callers should run this once at startup, before serving any
+// ReadTransaction_Fast/ReadReceipt_Fast lookups.
+func MigrateTxLookupEntries_Fast(db DatabaseReadWriter, headNumber uint64) {
+	version := ReadDatabaseVersion_Fast(db)
+	if version != nil && *version >= txLookupSchemaVersionFast {
+		return
+	}
+
+	var migrated uint64
+	for number := uint64(0); number <= headNumber; number++ {
+		hash := ReadCanonicalHash_Fast(db, number)
+		if hash == (common.Hash{}) {
+			break
+		}
+		block := ReadBlock_Fast(db, hash, number)
+		if block == nil {
+			break
+		}
+		WriteTxLookupEntries_Fast(db, block)
+		migrated++
+	}
+
+	WriteDatabaseVersion_Fast(db, txLookupSchemaVersionFast)
+	log.Info("Migrated fast chain tx lookup entries", "blocks", migrated)
+}
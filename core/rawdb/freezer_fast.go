@@ -0,0 +1,385 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// freezer_fast.go implements an ancient store for Fast chain data: once a
+// block is old enough that it can never be reorganised away, its header,
+// hash, body, receipts and total difficulty are migrated out of the active
+// key-value store into flat, append-only files (see freezer_table_fast.go)
+// and, apart from the canonical hash mapping, pruned from the KV store.
+// This keeps the live database small while
+// keeping old data available through the same ethdb.AncientReader surface.
+package rawdb
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/log"
+)
+
+// The ancient table names for Fast chain data, mirroring the block's
+// components: canonical hash, header, body and receipts are indexed per
+// block number; total difficulty rides alongside the header.
+const (
+	freezerHashTableFast       = "fast-hashes"
+	freezerHeaderTableFast     = "fast-headers"
+	freezerBodiesTableFast     = "fast-bodies"
+	freezerReceiptTableFast    = "fast-receipts"
+	freezerDifficultyTableFast = "fast-diffs"
+)
+
+// freezerFastTables lists every table a freezerFast manages, and whether its
+// items are already compact enough that snappy compression isn't worth it
+// (hashes and difficulties are tiny fixed-size values; headers, bodies and
+// receipts are RLP blobs worth compressing).
+var freezerFastTables = map[string]bool{
+	freezerHashTableFast:       true,
+	freezerHeaderTableFast:     false,
+	freezerBodiesTableFast:     false,
+	freezerReceiptTableFast:    false,
+	freezerDifficultyTableFast: true,
+}
+
+// freezerFastRecheckInterval is how often the background migration thread
+// looks for newly-immutable blocks to freeze.
+const freezerFastRecheckInterval = time.Minute
+
+// freezerFastImmutabilityThreshold is how many blocks must sit behind the
+// current head before they are considered old enough to freeze. It mirrors
+// the main chain's equivalent constant: deep enough that a reorg past this
+// point is not a case normal operation needs to handle.
+const freezerFastImmutabilityThreshold = 90000
+
+// freezerFast is the Fast chain's ancient store: a fixed set of append-only
+// tables plus a background thread that migrates data into them as it
+// becomes immutable.
+type freezerFast struct {
+	datadir string
+	tables  map[string]*freezerTableFast
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newFreezerFast opens (creating if necessary) a Fast chain ancient store
+// rooted at datadir.
+func newFreezerFast(datadir string) (*freezerFast, error) {
+	if err := os.MkdirAll(datadir, 0755); err != nil {
+		return nil, err
+	}
+	f := &freezerFast{
+		datadir: datadir,
+		tables:  make(map[string]*freezerTableFast, len(freezerFastTables)),
+		quit:    make(chan struct{}),
+	}
+	for name, noCompression := range freezerFastTables {
+		table, err := newFreezerTableFast(datadir, name, noCompression)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.tables[name] = table
+	}
+	if err := f.repair(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// repair truncates every table down to the item count of whichever table
+// has the fewest. FreezerBatchFast.Commit appends to all touched tables and
+// then fsyncs each in turn, not atomically across tables; a crash between
+// two of those fsyncs leaves the tables with different item counts for the
+// same logical batch. Since Ancients() only ever consults one table to
+// decide what's already frozen, a lagging table's missing items would
+// otherwise never be revisited by freezeOnce and would stay permanently
+// unreadable. Run once at startup, before anything else touches the store.
+func (f *freezerFast) repair() error {
+	min := uint64(0)
+	first := true
+	for _, table := range f.tables {
+		items := table.items()
+		if first || items < min {
+			min = items
+			first = false
+		}
+	}
+	for _, table := range f.tables {
+		if table.items() > min {
+			if err := table.truncate(min); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HasAncient returns whether the ancient store for kind has item number.
+func (f *freezerFast) HasAncient(kind string, number uint64) (bool, error) {
+	table, ok := f.tables[kind]
+	if !ok {
+		return false, nil
+	}
+	return table.has(number), nil
+}
+
+// Ancient retrieves an ancient blob from kind's table.
+func (f *freezerFast) Ancient(kind string, number uint64) ([]byte, error) {
+	table, ok := f.tables[kind]
+	if !ok {
+		return nil, errUnknownFreezerFastTable
+	}
+	if !table.has(number) {
+		return nil, nil
+	}
+	return table.retrieve(number)
+}
+
+// AncientRange retrieves up to count consecutive items from kind's table
+// starting at start, stopping early if maxBytes of data have been read.
+func (f *freezerFast) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	table, ok := f.tables[kind]
+	if !ok {
+		return nil, errUnknownFreezerFastTable
+	}
+	var (
+		blobs [][]byte
+		size  uint64
+	)
+	for i := uint64(0); i < count; i++ {
+		item := start + i
+		if !table.has(item) {
+			break
+		}
+		blob, err := table.retrieve(item)
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, blob)
+		size += uint64(len(blob))
+		if maxBytes > 0 && size >= maxBytes {
+			break
+		}
+	}
+	return blobs, nil
+}
+
+// Ancients returns the number of items already frozen (every table is kept
+// in lock-step, so any one of them answers this).
+func (f *freezerFast) Ancients() (uint64, error) {
+	table, ok := f.tables[freezerHeaderTableFast]
+	if !ok {
+		return 0, errUnknownFreezerFastTable
+	}
+	return table.items(), nil
+}
+
+// AncientSize reports the on-disk size of kind's data file.
+func (f *freezerFast) AncientSize(kind string) (uint64, error) {
+	table, ok := f.tables[kind]
+	if !ok {
+		return 0, errUnknownFreezerFastTable
+	}
+	return table.size()
+}
+
+// TruncateAncients discards every item from n onward, across all tables.
+func (f *freezerFast) TruncateAncients(n uint64) error {
+	for _, table := range f.tables {
+		if err := table.truncate(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync flushes every table to disk.
+func (f *freezerFast) Sync() error {
+	for _, table := range f.tables {
+		if err := table.sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the background migration thread, if running, and closes
+// every table.
+func (f *freezerFast) Close() error {
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+
+	var firstErr error
+	for _, table := range f.tables {
+		if err := table.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+var errUnknownFreezerFastTable = errors.New("rawdb: unknown fast freezer table")
+
+// FreezerBatchFast buffers a run of same-generation appends (one block's
+// worth of header/hash/body/receipts/td, or many) so the migration thread
+// can hand every table its items and fsync once per batch rather than once
+// per table per block.
+type FreezerBatchFast struct {
+	f       *freezerFast
+	pending map[string][]freezerBatchFastItem
+}
+
+type freezerBatchFastItem struct {
+	number uint64
+	blob   []byte
+}
+
+// NewBatch returns a FreezerBatchFast writing into f.
+func (f *freezerFast) NewBatch() *FreezerBatchFast {
+	return &FreezerBatchFast{f: f, pending: make(map[string][]freezerBatchFastItem)}
+}
+
+// AppendRaw buffers blob as item number in kind's table; it is not written
+// until Commit is called.
+func (b *FreezerBatchFast) AppendRaw(kind string, number uint64, blob []byte) error {
+	if _, ok := b.f.tables[kind]; !ok {
+		return errUnknownFreezerFastTable
+	}
+	b.pending[kind] = append(b.pending[kind], freezerBatchFastItem{number: number, blob: blob})
+	return nil
+}
+
+// Commit appends every buffered item to its table with a single WriteAt to
+// the data file and a single WriteAt to the index file per table (see
+// freezerTableFast.appendBatch), then fsyncs every touched table once.
+func (b *FreezerBatchFast) Commit() error {
+	touched := make(map[string]*freezerTableFast, len(b.pending))
+	for kind, items := range b.pending {
+		table := b.f.tables[kind]
+		if err := table.appendBatch(items); err != nil {
+			return err
+		}
+		touched[kind] = table
+	}
+	for _, table := range touched {
+		if err := table.sync(); err != nil {
+			return err
+		}
+	}
+	b.pending = make(map[string][]freezerBatchFastItem)
+	return nil
+}
+
+// StartFreezing launches the background thread that migrates immutable
+// Fast chain data out of db and into f, until f.Close is called.
+func (f *freezerFast) StartFreezing(db DatabaseDeleteReader) {
+	f.wg.Add(1)
+	go f.freezeLoop(db)
+}
+
+func (f *freezerFast) freezeLoop(db DatabaseDeleteReader) {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(freezerFastRecheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.quit:
+			return
+		case <-ticker.C:
+			if err := f.freezeOnce(db); err != nil {
+				log.Error("Fast freezer migration cycle failed", "err", err)
+			}
+		}
+	}
+}
+
+// freezeOnce migrates every canonical block old enough to have passed
+// freezerFastImmutabilityThreshold, starting from whatever has already been
+// frozen, then deletes the migrated entries from db.
+func (f *freezerFast) freezeOnce(db DatabaseDeleteReader) error {
+	head := ReadHeadBlockHash_Fast(db)
+	if head == (common.Hash{}) {
+		return nil
+	}
+	headNumber := ReadHeaderNumber_Fast(db, head)
+	if headNumber == nil || *headNumber <= freezerFastImmutabilityThreshold {
+		return nil
+	}
+	limit := *headNumber - freezerFastImmutabilityThreshold
+
+	frozen, err := f.Ancients()
+	if err != nil {
+		return err
+	}
+
+	batch := f.NewBatch()
+	migrated := make([]uint64, 0)
+	for number := frozen; number < limit; number++ {
+		hash := ReadCanonicalHash_Fast(db, number)
+		if hash == (common.Hash{}) {
+			break // not canonical (or not present) yet, nothing more to do this cycle
+		}
+		header := ReadHeaderRLP_Fast(db, hash, number)
+		body := ReadBodyRLP_Fast(db, hash, number)
+		if len(header) == 0 || len(body) == 0 {
+			break
+		}
+		receipts, _ := db.Get(blockReceiptsKeyFast(number, hash))
+		td, _ := db.Get(headerTDKeyFast(number, hash))
+
+		if err := batch.AppendRaw(freezerHashTableFast, number, hash.Bytes()); err != nil {
+			return err
+		}
+		if err := batch.AppendRaw(freezerHeaderTableFast, number, header); err != nil {
+			return err
+		}
+		if err := batch.AppendRaw(freezerBodiesTableFast, number, body); err != nil {
+			return err
+		}
+		if err := batch.AppendRaw(freezerReceiptTableFast, number, receipts); err != nil {
+			return err
+		}
+		if err := batch.AppendRaw(freezerDifficultyTableFast, number, td); err != nil {
+			return err
+		}
+		migrated = append(migrated, number)
+	}
+	if len(migrated) == 0 {
+		return nil
+	}
+	if err := batch.Commit(); err != nil {
+		return err
+	}
+
+	for _, number := range migrated {
+		hash := ReadCanonicalHash_Fast(db, number)
+		DeleteHeader_Fast(db, hash, number)
+		DeleteBody_Fast(db, hash, number)
+		DeleteReceipts_Fast(db, hash, number)
+		DeleteTd_Fast(db, hash, number)
+	}
+	log.Info("Froze Fast chain data", "from", frozen, "to", migrated[len(migrated)-1])
+	return nil
+}
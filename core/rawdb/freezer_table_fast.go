@@ -0,0 +1,235 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+// freezerTableFast is a single append-only, item-indexed series of ancient
+// data (e.g. "all Fast chain headers"). Items are numbered from zero and can
+// only be appended in order; the on-disk layout is a flat data file plus an
+// index file of (uint32) end-offsets, one per stored item, so that item i's
+// bytes are data[offsets[i]:offsets[i+1]].
+//
+// This trades away upstream geth's freezer_table.go file-rotation (splitting
+// a table across many bounded-size files) for a single pair of files per
+// table. Fast chain ancient data is not expected to approach the 2GB
+// rotation threshold that optimization exists for.
+type freezerTableFast struct {
+	mu sync.RWMutex
+
+	name          string
+	noCompression bool
+
+	data *os.File
+	idx  *os.File
+
+	// offsets[i] is the end offset (into data) of item i; offsets[0] is
+	// always 0. len(offsets) == items+1.
+	offsets []uint32
+}
+
+// newFreezerTableFast opens (creating if necessary) the data and index files
+// for name under dir, and rebuilds the in-memory offset index from the
+// on-disk index file.
+func newFreezerTableFast(dir, name string, noCompression bool) (*freezerTableFast, error) {
+	dataPath := freezerTableFastDataPath(dir, name)
+	idxPath := freezerTableFastIdxPath(dir, name)
+
+	data, err := os.OpenFile(dataPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("rawdb: open fast freezer table %q data file: %v", name, err)
+	}
+	idx, err := os.OpenFile(idxPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		data.Close()
+		return nil, fmt.Errorf("rawdb: open fast freezer table %q index file: %v", name, err)
+	}
+
+	t := &freezerTableFast{
+		name:          name,
+		noCompression: noCompression,
+		data:          data,
+		idx:           idx,
+		offsets:       []uint32{0},
+	}
+	if err := t.loadIndex(); err != nil {
+		data.Close()
+		idx.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func freezerTableFastDataPath(dir, name string) string { return dir + "/" + name + ".fcdat" }
+func freezerTableFastIdxPath(dir, name string) string  { return dir + "/" + name + ".cidx" }
+
+// loadIndex replays the on-disk index file into t.offsets.
+func (t *freezerTableFast) loadIndex() error {
+	info, err := t.idx.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size()%4 != 0 {
+		return fmt.Errorf("rawdb: fast freezer table %q index file is corrupt: size %d not a multiple of 4", t.name, info.Size())
+	}
+	raw := make([]byte, info.Size())
+	if _, err := t.idx.ReadAt(raw, 0); err != nil {
+		return err
+	}
+	for i := 0; i+4 <= len(raw); i += 4 {
+		t.offsets = append(t.offsets, binary.BigEndian.Uint32(raw[i:i+4]))
+	}
+	return nil
+}
+
+// items returns the number of items currently stored in the table.
+func (t *freezerTableFast) items() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return uint64(len(t.offsets) - 1)
+}
+
+// has reports whether item is already present in the table.
+func (t *freezerTableFast) has(item uint64) bool {
+	return item < t.items()
+}
+
+// retrieve returns the (decompressed) blob stored at item.
+func (t *freezerTableFast) retrieve(item uint64) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if item+1 >= uint64(len(t.offsets)) {
+		return nil, fmt.Errorf("rawdb: fast freezer table %q has no item %d", t.name, item)
+	}
+	start, end := t.offsets[item], t.offsets[item+1]
+	raw := make([]byte, end-start)
+	if _, err := t.data.ReadAt(raw, int64(start)); err != nil {
+		return nil, err
+	}
+	if t.noCompression {
+		return raw, nil
+	}
+	return snappy.Decode(nil, raw)
+}
+
+// appendBatch stores a run of items, in order, starting at t.items(): the
+// freezer is strictly append-only. All of the batch's (compressed) bytes
+// are written to the data file in a single WriteAt, and all of its new
+// offsets are written to the index file in a single WriteAt, so a batch of
+// N items costs two syscalls total rather than 2N - the per-item WriteAt
+// pair this replaced was what actually dominated batch-write cost, since
+// Commit was already fsyncing only once per table.
+func (t *freezerTableFast) appendBatch(items []freezerBatchFastItem) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	want := uint64(len(t.offsets) - 1)
+	start := t.offsets[len(t.offsets)-1]
+
+	var data bytes.Buffer
+	offsets := make([]uint32, 0, len(items))
+	end := start
+	for _, it := range items {
+		if it.number != want {
+			return fmt.Errorf("rawdb: fast freezer table %q out-of-order append: have %d, want %d", t.name, it.number, want)
+		}
+		want++
+
+		raw := it.blob
+		if !t.noCompression {
+			raw = snappy.Encode(nil, it.blob)
+		}
+		data.Write(raw)
+		end += uint32(len(raw))
+		offsets = append(offsets, end)
+	}
+
+	if _, err := t.data.WriteAt(data.Bytes(), int64(start)); err != nil {
+		return err
+	}
+
+	idx := make([]byte, 4*len(offsets))
+	for i, off := range offsets {
+		binary.BigEndian.PutUint32(idx[4*i:], off)
+	}
+	if _, err := t.idx.WriteAt(idx, int64(4*(len(t.offsets)-1))); err != nil {
+		return err
+	}
+
+	t.offsets = append(t.offsets, offsets...)
+	return nil
+}
+
+// truncate discards every item from items onward, rewinding both files.
+func (t *freezerTableFast) truncate(items uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if items >= uint64(len(t.offsets)-1) {
+		return nil
+	}
+	dataSize := int64(t.offsets[items])
+	if err := t.data.Truncate(dataSize); err != nil {
+		return err
+	}
+	if err := t.idx.Truncate(int64(4 * items)); err != nil {
+		return err
+	}
+	t.offsets = t.offsets[:items+1]
+	return nil
+}
+
+func (t *freezerTableFast) sync() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.idx.Sync()
+}
+
+func (t *freezerTableFast) size() (uint64, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	info, err := t.data.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(info.Size()), nil
+}
+
+func (t *freezerTableFast) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.data.Close(); err != nil {
+		return err
+	}
+	return t.idx.Close()
+}
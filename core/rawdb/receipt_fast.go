@@ -0,0 +1,144 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// receipt_fast.go defines the Fast chain's compact on-disk receipt format.
+// types.Receipt's own RLP encoding (and the ReceiptForStorage type before
+// this file) stores PostState/Status, CumulativeGasUsed, Bloom and Logs.
+// Bloom is fully derivable from Logs, so ReceiptForStorageFast drops it (and
+// strips every per-log field but Address/Topics/Data, which are likewise
+// redundant with the owning block and transaction) before writing to disk.
+// ReadReceipts_Fast rehydrates everything this drops.
+package rawdb
+
+import (
+	"io"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/core/types"
+	"github.com/truechain/truechain-engineering-code/crypto"
+	"github.com/truechain/truechain-engineering-code/rlp"
+)
+
+var (
+	receiptStatusFailedRLPFast     = []byte{}
+	receiptStatusSuccessfulRLPFast = []byte{0x01}
+)
+
+// receiptStorageFastRLP is the on-disk shape a ReceiptForStorageFast
+// encodes to and decodes from.
+type receiptStorageFastRLP struct {
+	PostStateOrStatus []byte
+	CumulativeGasUsed uint64
+	Logs              []*logForStorageFast
+}
+
+// logForStorageFast is the on-disk shape of a single log entry: just the
+// three fields a log's RLP hash commits to. BlockHash, BlockNumber, TxHash,
+// TxIndex and Index are all recomputed from the owning receipt/block on
+// read.
+type logForStorageFast struct {
+	Address common.Address
+	Topics  []common.Hash
+	Data    []byte
+}
+
+// ReceiptForStorageFast wraps a types.Receipt so that RLP encoding/decoding
+// uses the compact receiptStorageFastRLP form instead of the receipt's own
+// (larger) consensus encoding.
+type ReceiptForStorageFast types.Receipt
+
+// EncodeRLP implements rlp.Encoder.
+func (r *ReceiptForStorageFast) EncodeRLP(w io.Writer) error {
+	enc := &receiptStorageFastRLP{CumulativeGasUsed: r.CumulativeGasUsed}
+	switch {
+	case len(r.PostState) > 0:
+		enc.PostStateOrStatus = r.PostState
+	case r.Status == types.ReceiptStatusFailed:
+		enc.PostStateOrStatus = receiptStatusFailedRLPFast
+	default:
+		enc.PostStateOrStatus = receiptStatusSuccessfulRLPFast
+	}
+	enc.Logs = make([]*logForStorageFast, len(r.Logs))
+	for i, l := range r.Logs {
+		enc.Logs[i] = &logForStorageFast{Address: l.Address, Topics: l.Topics, Data: l.Data}
+	}
+	return rlp.Encode(w, enc)
+}
+
+// DecodeRLP implements rlp.Decoder, and recomputes Bloom from the decoded
+// logs; callers needing the rest (TxHash, ContractAddress, GasUsed, per-log
+// block/tx/index fields) must call deriveReceiptFieldsFast afterwards once
+// the owning block's transactions are available.
+func (r *ReceiptForStorageFast) DecodeRLP(s *rlp.Stream) error {
+	var dec receiptStorageFastRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	switch len(dec.PostStateOrStatus) {
+	case 0:
+		r.Status = types.ReceiptStatusFailed
+	case 1:
+		r.Status = types.ReceiptStatusSuccessful
+	default:
+		r.PostState = dec.PostStateOrStatus
+	}
+	r.CumulativeGasUsed = dec.CumulativeGasUsed
+	r.Logs = make([]*types.Log, len(dec.Logs))
+	for i, l := range dec.Logs {
+		r.Logs[i] = &types.Log{Address: l.Address, Topics: l.Topics, Data: l.Data}
+	}
+	r.Bloom = types.CreateBloom(types.Receipts{(*types.Receipt)(r)})
+	return nil
+}
+
+// deriveReceiptFieldsFast fills in every field ReceiptForStorageFast's RLP
+// form omits: each receipt's TxHash, GasUsed (by differencing consecutive
+// CumulativeGasUsed values), ContractAddress (for contract-creation
+// transactions), and every log's BlockHash/BlockNumber/TxHash/TxIndex/Index.
+// txs must be the transactions of the block (hash, number) belongs to, in
+// order; receipts failing to line up with txs are left as decoded.
+func deriveReceiptFieldsFast(receipts types.Receipts, hash common.Hash, number uint64, txs types.Transactions) {
+	var prevGasUsed uint64
+	logIndex := uint(0)
+
+	for i, receipt := range receipts {
+		if i >= len(txs) {
+			break
+		}
+		tx := txs[i]
+
+		receipt.TxHash = tx.Hash()
+		receipt.GasUsed = receipt.CumulativeGasUsed - prevGasUsed
+		prevGasUsed = receipt.CumulativeGasUsed
+
+		if tx.To() == nil {
+			if signer := types.NewEIP155Signer(tx.ChainId()); true {
+				if from, err := types.Sender(signer, tx); err == nil {
+					receipt.ContractAddress = crypto.CreateAddress(from, tx.Nonce())
+				}
+			}
+		}
+
+		for _, log := range receipt.Logs {
+			log.BlockNumber = number
+			log.BlockHash = hash
+			log.TxHash = receipt.TxHash
+			log.TxIndex = uint(i)
+			log.Index = logIndex
+			logIndex++
+		}
+	}
+}
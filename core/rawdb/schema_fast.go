@@ -0,0 +1,101 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// schema_fast.go lists the database key prefixes and markers used by every
+// *_Fast accessor in this package. Keeping them in one file makes the key
+// space easy to audit for collisions, the same way go-ethereum's schema.go
+// does for the main chain.
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/truechain/truechain-engineering-code/common"
+)
+
+var (
+	// headHeaderKeyFast tracks the latest known header.
+	headHeaderKeyFast = []byte("LastHeaderFast")
+	// headBlockKeyFast tracks the latest known full block's hash.
+	headBlockKeyFast = []byte("LastBlockFast")
+	// headFastBlockKeyFast tracks the latest known incomplete block's hash.
+	headFastBlockKeyFast = []byte("LastFastFast")
+
+	// databaseVersionKeyFast tracks the Fast chain's schema version.
+	databaseVersionKeyFast = []byte("DatabaseVersionFast")
+	// genesisHashKeyFast tracks the Fast chain's genesis block hash.
+	genesisHashKeyFast = []byte("GenesisHashFast")
+
+	headerPrefixFast       = []byte("fh") // headerPrefixFast + num (uint64 big endian) + hash -> header
+	headerTDSuffixFast     = []byte("ft") // headerPrefixFast + num + hash + headerTDSuffixFast -> td
+	headerHashSuffixFast   = []byte("fn") // headerPrefixFast + num + headerHashSuffixFast -> hash
+	headerNumberPrefixFast = []byte("fH") // headerNumberPrefixFast + hash -> num
+
+	blockBodyPrefixFast     = []byte("fb") // blockBodyPrefixFast + num + hash -> body
+	blockReceiptsPrefixFast = []byte("fr") // blockReceiptsPrefixFast + num + hash -> receipts
+
+	txLookupPrefixFast = []byte("fl") // txLookupPrefixFast + hash -> transaction/receipt lookup metadata
+
+	configPrefixFast = []byte("fast-ethereum-config-") // configPrefixFast + hash -> chain config
+)
+
+// encodeBlockNumberFast encodes a block number as big endian uint64, matching
+// the main chain's accessor key layout so Fast keys sort the same way.
+func encodeBlockNumberFast(number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return enc
+}
+
+// headerKeyFast = headerPrefixFast + num (uint64 big endian) + hash
+func headerKeyFast(number uint64, hash common.Hash) []byte {
+	return append(append(headerPrefixFast, encodeBlockNumberFast(number)...), hash.Bytes()...)
+}
+
+// headerTDKeyFast = headerPrefixFast + num (uint64 big endian) + hash + headerTDSuffixFast
+func headerTDKeyFast(number uint64, hash common.Hash) []byte {
+	return append(headerKeyFast(number, hash), headerTDSuffixFast...)
+}
+
+// headerHashKeyFast = headerPrefixFast + num (uint64 big endian) + headerHashSuffixFast
+func headerHashKeyFast(number uint64) []byte {
+	return append(append(headerPrefixFast, encodeBlockNumberFast(number)...), headerHashSuffixFast...)
+}
+
+// headerNumberKeyFast = headerNumberPrefixFast + hash
+func headerNumberKeyFast(hash common.Hash) []byte {
+	return append(headerNumberPrefixFast, hash.Bytes()...)
+}
+
+// blockBodyKeyFast = blockBodyPrefixFast + num (uint64 big endian) + hash
+func blockBodyKeyFast(number uint64, hash common.Hash) []byte {
+	return append(append(blockBodyPrefixFast, encodeBlockNumberFast(number)...), hash.Bytes()...)
+}
+
+// blockReceiptsKeyFast = blockReceiptsPrefixFast + num (uint64 big endian) + hash
+func blockReceiptsKeyFast(number uint64, hash common.Hash) []byte {
+	return append(append(blockReceiptsPrefixFast, encodeBlockNumberFast(number)...), hash.Bytes()...)
+}
+
+// txLookupKeyFast = txLookupPrefixFast + hash
+func txLookupKeyFast(hash common.Hash) []byte {
+	return append(txLookupPrefixFast, hash.Bytes()...)
+}
+
+// configKeyFast = configPrefixFast + hash
+func configKeyFast(hash common.Hash) []byte {
+	return append(configPrefixFast, hash.Bytes()...)
+}
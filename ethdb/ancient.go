@@ -0,0 +1,62 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+// AncientReader contains the methods required to read from immutable
+// ancient data, the "frozen" tail of a chain that is old enough it will
+// never be reorganised away. Data here is indexed by an ever-increasing
+// item number rather than by hash, since ancient items are append-only.
+type AncientReader interface {
+	// HasAncient returns an indicator whether the specified data exists in
+	// the ancient store.
+	HasAncient(kind string, number uint64) (bool, error)
+
+	// Ancient retrieves an ancient binary blob from the append-only
+	// immutable data store.
+	Ancient(kind string, number uint64) ([]byte, error)
+
+	// AncientRange retrieves multiple items in sequence, starting from the
+	// index 'start'. It will return at most 'count' items, but will abort
+	// early if the total size of the returned data exceeds 'maxBytes'.
+	AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error)
+
+	// Ancients returns the ancient item numbering, i.e. the first item that
+	// is not yet in the ancient store.
+	Ancients() (uint64, error)
+
+	// AncientSize returns the ancient size for the specified category.
+	AncientSize(kind string) (uint64, error)
+}
+
+// AncientWriter contains the methods required to write to immutable ancient
+// data.
+type AncientWriter interface {
+	// TruncateAncients discards all but the first n ancient items from the
+	// ancient store. After truncation, the ancient size of each category is
+	// n.
+	TruncateAncients(n uint64) error
+
+	// Sync flushes all in-memory ancient store data to disk.
+	Sync() error
+}
+
+// AncientStore contains all the methods required to allow access to the
+// ancient store.
+type AncientStore interface {
+	AncientReader
+	AncientWriter
+}
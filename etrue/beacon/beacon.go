@@ -0,0 +1,66 @@
+// Package beacon provides a source of fresh, unbiasable randomness for
+// committee election: a chain of signed entries that nobody can predict
+// before its round is reached, nor bias after the fact without breaking
+// the underlying threshold signature.
+package beacon
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+)
+
+// BeaconEntry is one link in the randomness chain. Randomness is derived
+// from Signature, which in turn signs Round and the previous entry's
+// signature, so each entry is both unpredictable ahead of time and
+// cryptographically bound to the one before it.
+type BeaconEntry struct {
+	Round       uint64
+	Randomness  []byte
+	Signature   []byte
+	PreviousSig []byte
+}
+
+// Beacon is a source of BeaconEntry values, indexed by round.
+// Implementations must make Entry safe for concurrent use.
+type Beacon interface {
+	// Entry returns the entry for round, fetching and verifying it against
+	// the chain if it isn't already known.
+	Entry(ctx context.Context, round uint64) (BeaconEntry, error)
+
+	// VerifyEntry checks that curr legitimately follows prev: that
+	// curr.Signature is a valid group signature over H(curr.Round ||
+	// prev.Signature), and curr.PreviousSig == prev.Signature.
+	VerifyEntry(prev, curr BeaconEntry) error
+
+	// NewEntries returns a channel that receives every new entry as soon
+	// as it is observed, so callers can trigger committee rotation without
+	// polling.
+	NewEntries() <-chan BeaconEntry
+
+	// LatestRound returns the highest round this Beacon has observed.
+	LatestRound() uint64
+}
+
+// ErrUnknownRound is returned by Entry when asked for a round this Beacon
+// has not produced or fetched yet and cannot derive locally.
+var ErrUnknownRound = errors.New("beacon: unknown round")
+
+// roundBytes is the canonical big-endian encoding of a round number, used
+// as part of what gets hashed/signed for every entry.
+func roundBytes(round uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, round)
+	return b
+}
+
+// SeedForEpoch derives the seed Sortition should use for epoch, scoped to
+// role so different committee roles (proposer, voter, ...) never draw
+// against the same randomness. It is the only place the rest of the
+// codebase needs to reach into a BeaconEntry's raw Randomness.
+func SeedForEpoch(entry BeaconEntry, epoch uint64, role string) []byte {
+	seed := append([]byte{}, entry.Randomness...)
+	seed = append(seed, roundBytes(epoch)...)
+	seed = append(seed, role...)
+	return seed
+}
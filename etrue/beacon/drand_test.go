@@ -0,0 +1,89 @@
+package beacon
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drand/kyber/pairing/bn256"
+	"github.com/drand/kyber/sign/bls"
+	"github.com/drand/kyber/util/random"
+)
+
+// drandTestChain builds a one-round drand-style chain: a BLS keypair, plus a
+// server that serves round 1 signed under that keypair, so DrandBeacon.Entry
+// can be exercised against something that looks like the real HTTP API (hex
+// strings, not raw bytes).
+func drandTestChain(t *testing.T) (server *httptest.Server, groupKeyHex string) {
+	t.Helper()
+
+	suite := bn256.NewSuite()
+	scheme := bls.NewSchemeOnG1(suite)
+	secret, public := scheme.NewKeyPair(random.New())
+
+	publicBytes, err := public.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal group public key: %v", err)
+	}
+	groupKeyHex = hex.EncodeToString(publicBytes)
+
+	previousSig := []byte("genesis")
+	msg := signedMessage(1, previousSig)
+	sig, err := scheme.Sign(secret, msg)
+	if err != nil {
+		t.Fatalf("sign round 1: %v", err)
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"round":1,"randomness":"` + hex.EncodeToString([]byte("round-1-randomness")) + `",` +
+			`"signature":"` + hex.EncodeToString(sig) + `","previous_signature":"` + hex.EncodeToString(previousSig) + `"}`))
+	}))
+	return server, groupKeyHex
+}
+
+func TestDrandBeaconEntryDecodesHexAndVerifies(t *testing.T) {
+	server, groupKeyHex := drandTestChain(t)
+	defer server.Close()
+
+	d, err := NewDrandBeacon(server.URL, groupKeyHex)
+	if err != nil {
+		t.Fatalf("NewDrandBeacon: %v", err)
+	}
+
+	entry, err := d.Entry(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Entry: %v", err)
+	}
+	if string(entry.Randomness) != "round-1-randomness" {
+		t.Fatalf("Randomness = %q, want decoded hex payload", entry.Randomness)
+	}
+	if string(entry.PreviousSig) != "genesis" {
+		t.Fatalf("PreviousSig = %q, want decoded hex payload", entry.PreviousSig)
+	}
+}
+
+func TestDrandBeaconEntryRejectsBadSignature(t *testing.T) {
+	server, _ := drandTestChain(t)
+	defer server.Close()
+
+	// Point the client at a different, unrelated group key so the
+	// server's genuine signature no longer verifies.
+	otherSuite := bn256.NewSuite()
+	_, otherPublic := bls.NewSchemeOnG1(otherSuite).NewKeyPair(random.New())
+	otherPublicBytes, err := otherPublic.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal other group public key: %v", err)
+	}
+
+	d, err := NewDrandBeacon(server.URL, hex.EncodeToString(otherPublicBytes))
+	if err != nil {
+		t.Fatalf("NewDrandBeacon: %v", err)
+	}
+
+	if _, err := d.Entry(context.Background(), 1); err == nil {
+		t.Fatal("Entry accepted a signature that does not verify under the configured group key")
+	}
+}
@@ -0,0 +1,107 @@
+package beacon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// LocalBeacon is a deterministic, single-process stand-in for DrandBeacon,
+// for tests and local networks where running an actual threshold-BLS
+// randomness chain would be overkill. Entries are generated on demand from
+// a fixed secret rather than fetched, but still chain together the same
+// way a real beacon's entries do, so code written against Beacon behaves
+// identically against either implementation.
+type LocalBeacon struct {
+	secret []byte
+
+	mu      sync.Mutex
+	latest  uint64
+	entries map[uint64]BeaconEntry
+	subs    []chan BeaconEntry
+}
+
+// NewLocalBeacon builds a LocalBeacon seeded by secret. Two LocalBeacons
+// built with the same secret produce an identical chain of entries.
+func NewLocalBeacon(secret []byte) *LocalBeacon {
+	b := &LocalBeacon{
+		secret:  append([]byte{}, secret...),
+		entries: make(map[uint64]BeaconEntry),
+	}
+	genesis := b.deriveRandomness(0, nil)
+	b.entries[0] = BeaconEntry{Round: 0, Randomness: genesis, Signature: genesis}
+	return b
+}
+
+func (b *LocalBeacon) deriveRandomness(round uint64, previousSig []byte) []byte {
+	mac := hmac.New(sha256.New, b.secret)
+	mac.Write(roundBytes(round))
+	mac.Write(previousSig)
+	return mac.Sum(nil)
+}
+
+// Entry deterministically derives (and caches) every round up to the one
+// requested, chaining each from the last.
+func (b *LocalBeacon) Entry(ctx context.Context, round uint64) (BeaconEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.entries[round]; ok {
+		return e, nil
+	}
+
+	// Entries are only ever added contiguously from round 0, so every round
+	// up to b.latest is cached and round lies past it.
+	var entry BeaconEntry
+	for r := b.latest + 1; r <= round; r++ {
+		prev := b.entries[r-1]
+		randomness := b.deriveRandomness(r, prev.Randomness)
+		entry = BeaconEntry{
+			Round:       r,
+			Randomness:  randomness,
+			Signature:   randomness, // local beacon has no real signature scheme
+			PreviousSig: prev.Randomness,
+		}
+		b.entries[r] = entry
+		b.latest = r
+
+		for _, ch := range b.subs {
+			select {
+			case ch <- entry:
+			default:
+			}
+		}
+	}
+	return entry, nil
+}
+
+// VerifyEntry checks curr was derived from prev by recomputing it.
+func (b *LocalBeacon) VerifyEntry(prev, curr BeaconEntry) error {
+	if curr.Round != prev.Round+1 {
+		return fmt.Errorf("beacon: round %d does not follow round %d", curr.Round, prev.Round)
+	}
+	want := b.deriveRandomness(curr.Round, prev.Randomness)
+	if !hmac.Equal(want, curr.Randomness) {
+		return fmt.Errorf("beacon: round %d randomness does not match its chain", curr.Round)
+	}
+	if !hmac.Equal(curr.PreviousSig, prev.Signature) {
+		return fmt.Errorf("beacon: round %d does not chain from round %d's signature", curr.Round, prev.Round)
+	}
+	return nil
+}
+
+func (b *LocalBeacon) NewEntries() <-chan BeaconEntry {
+	ch := make(chan BeaconEntry, 8)
+	b.mu.Lock()
+	b.subs = append(b.subs, ch)
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *LocalBeacon) LatestRound() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.latest
+}
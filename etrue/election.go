@@ -1,76 +1,76 @@
 package etrue
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/sha256"
 	"math/big"
-)
 
-var z  = 10000
-var k  = 50000
-var P  = 100
+	"github.com/truechain/truechain-engineering-code/etrue/beacon"
+)
 
+// VoteuUse holds one miner's local sortition state: the committee weight
+// it controls (wi, out of a total W with expected size threshold) and the
+// outcome of running Sortition over the current election seed.
 type VoteuUse struct {
-	wi 	int64  //Local value
-	seed string
-	b   bool
-	j 	int
-
-
+	wi        int64 // local weight (stake-derived sub-units)
+	threshold int64 // expected committee size
+	W         int64 // total weight across all miners
+	role      string
+	seed      []byte
+
+	hash     []byte
+	proof    []byte
+	j        int
+	selected bool
 }
-//Calculate your own force unit locally
-func (v VoteuUse)LocalForce()int64{
-
-
-	w := v.wi
-	//w_i=(D_pf-〖[h]〗_(-k))/u
-	return w
 
+// NewVoteuUse builds the local sortition input for one election round.
+func NewVoteuUse(wi, threshold, W int64, role string, seed []byte) *VoteuUse {
+	return &VoteuUse{wi: wi, threshold: threshold, W: W, role: role, seed: seed}
 }
 
-
-//The power function used by the draw function
-func powerf(x float64, n int) float64 {
-	ans := 1.0
-
-	for n != 0 {
-		if n%2 == 1 {
-			ans *= x
-		}
-		x *= x
-		n /= 2
+// NewVoteuUseFromBeacon builds the local sortition input for epoch using
+// the beacon's entry for that epoch as the unpredictable seed, rather than
+// a caller-supplied one. This is the path committee election should use in
+// production: it guarantees the seed can't be grinded by whoever proposes
+// it, since it's fixed by the beacon before any miner runs Sortition.
+func NewVoteuUseFromBeacon(ctx context.Context, b beacon.Beacon, epoch uint64, wi, threshold, W int64, role string) (*VoteuUse, error) {
+	entry, err := b.Entry(ctx, epoch)
+	if err != nil {
+		return nil, err
 	}
-	return ans
+	seed := beacon.SeedForEpoch(entry, epoch, role)
+	return NewVoteuUse(wi, threshold, W, role, seed), nil
 }
 
-//Factorial function
-func Factorial(){
+// LocalForce returns this miner's local committee weight.
+func (v *VoteuUse) LocalForce() int64 {
+	return v.wi
+}
 
+// Draw runs the VRF sortition draw for this round with sk and caches the
+// result so Hash/Proof/Selected can be read back and gossiped to the rest
+// of the committee.
+func (v *VoteuUse) Draw(sk *ecdsa.PrivateKey) (j int, selected bool) {
+	v.hash, v.proof, v.j, v.selected = Sortition(sk, v.seed, v.threshold, v.wi, v.W, v.role)
+	return v.j, v.selected
 }
 
-//The sum function
-func Sigma(j int,k int,wi int,P int64) {
+// Hash returns the VRF output produced by the last Draw.
+func (v *VoteuUse) Hash() []byte { return v.hash }
 
-}
+// Proof returns the VRF proof produced by the last Draw.
+func (v *VoteuUse) Proof() []byte { return v.proof }
 
-// the draw function is calculated locally for each miner
-// the parameters seed, w_i, W, P are required
-
-//func (v VoteuUse)Sortition()int,bool{
-//j := 0;
-//
-//for (seed / powerf(2,seedlen)) ^ [Sigma(j,0,wi,P) , Sigma(j+1,0,wi,P)]{
-//
-//j++;
-//
-//if  j > N {
-//return j,true;
-//	}
-//}
-//	return _,false;
-//
-//}
+// Selected reports whether the last Draw won any committee seats.
+func (v *VoteuUse) Selected() bool { return v.selected }
 
+// VerifyDraw checks a peer's claimed (hash, proof, j) against its public
+// key and this round's parameters.
+func (v *VoteuUse) VerifyDraw(pk *ecdsa.PublicKey, hash, proof []byte) (j int, ok bool) {
+	return VerifySortition(pk, v.seed, hash, proof, v.threshold, v.wi, v.W, v.role)
+}
 
 // Verify checks a raw ECDSA signature.
 // Returns true if it's valid and false if not.
@@ -86,4 +86,3 @@ func Verify(data, signature []byte, pubkey *ecdsa.PublicKey) bool {
 
 	return ecdsa.Verify(pubkey, digest[:], r, s)
 }
-
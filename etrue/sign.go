@@ -0,0 +1,25 @@
+package etrue
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+)
+
+// Sign produces a raw ECDSA signature over data, in the fixed-width
+// r||s encoding Verify expects: each of r and s is left-padded to the
+// curve's order byte size.
+func Sign(data []byte, sk *ecdsa.PrivateKey) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	r, s, err := ecdsa.Sign(rand.Reader, sk, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	curveOrderByteSize := sk.Curve.Params().P.BitLen() / 8
+	signature := make([]byte, 2*curveOrderByteSize)
+	r.FillBytes(signature[:curveOrderByteSize])
+	s.FillBytes(signature[curveOrderByteSize:])
+	return signature, nil
+}
@@ -0,0 +1,249 @@
+package etrue
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// Sortition implements Algorand-style cryptographic sortition: it draws a
+// VRF output over seed||role with sk, then uses that output to decide how
+// many of the wi "sub-units" owned by this miner are selected, out of a
+// total committee weight W with expected committee size threshold.
+//
+// hash and proof are the VRF output and its proof, independently checkable
+// by anyone holding the matching public key via VerifySortition. j is the
+// number of selected sub-units; selected reports whether j > 0.
+func Sortition(sk *ecdsa.PrivateKey, seed []byte, threshold, wi, W int64, role string) (hash []byte, proof []byte, j int, selected bool) {
+	hash, proof, err := vrfProve(sk, vrfInput(seed, role))
+	if err != nil {
+		return nil, nil, 0, false
+	}
+
+	j = sortitionCount(hash, threshold, wi, W)
+	return hash, proof, j, j > 0
+}
+
+// VerifySortition checks that (hash, proof) is a valid VRF output for pk
+// over seed||role, then recomputes j exactly as Sortition did so a verifier
+// can confirm the claimed committee weight without trusting the prover.
+func VerifySortition(pk *ecdsa.PublicKey, seed []byte, hash, proof []byte, threshold, wi, W int64, role string) (j int, ok bool) {
+	if !vrfVerify(pk, vrfInput(seed, role), hash, proof) {
+		return 0, false
+	}
+	return sortitionCount(hash, threshold, wi, W), true
+}
+
+func vrfInput(seed []byte, role string) []byte {
+	return append(append([]byte{}, seed...), role...)
+}
+
+// sortitionCount interprets hash as a uniform sample in [0,1) and returns
+// the unique j in [0, wi] such that the sample falls in
+// [B(j-1; wi, p), B(j; wi, p)), where B is the CDF of Binomial(wi, p),
+// B(-1) = 0, and p = threshold/W. The CDF is accumulated incrementally with big.Float so
+// it stays well-behaved for the large wi values a miner's stake implies.
+// Each term P(X = j) is itself derived from the previous one via the
+// standard binomial recurrence (see nextBinomialPMF) rather than
+// recomputed from scratch, which would cost O(wi) big-integer work per
+// term and O(wi^2) overall for the large wi a miner's stake can reach.
+func sortitionCount(hash []byte, threshold, wi, W int64) int {
+	if wi <= 0 || W <= 0 || len(hash) == 0 {
+		return 0
+	}
+
+	sample := new(big.Float).SetInt(new(big.Int).SetBytes(hash))
+	denom := new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(len(hash)*8)))
+	sample.Quo(sample, denom)
+
+	p := new(big.Float).Quo(big.NewFloat(float64(threshold)), big.NewFloat(float64(W)))
+	q := new(big.Float).Sub(big.NewFloat(1), p)
+
+	cdf := new(big.Float)
+	pmf := bigFloatPow(q, wi) // P(X = 0) = q^wi
+	for j := int64(0); j <= wi; j++ {
+		cdf.Add(cdf, pmf)
+		if sample.Cmp(cdf) < 0 {
+			return int(j)
+		}
+		pmf = nextBinomialPMF(pmf, j, wi, p, q)
+	}
+	return int(wi)
+}
+
+// nextBinomialPMF advances pmf = P(X = j) to P(X = j+1) for X ~
+// Binomial(n, p), via the recurrence P(j+1) = P(j) * (n-j)/(j+1) * p/q.
+func nextBinomialPMF(pmf *big.Float, j, n int64, p, q *big.Float) *big.Float {
+	next := new(big.Float).Mul(pmf, big.NewFloat(float64(n-j)))
+	next.Quo(next, big.NewFloat(float64(j+1)))
+	next.Mul(next, p)
+	next.Quo(next, q)
+	return next
+}
+
+func bigFloatPow(x *big.Float, n int64) *big.Float {
+	result := big.NewFloat(1)
+	base := new(big.Float).Copy(x)
+	for n > 0 {
+		if n&1 == 1 {
+			result.Mul(result, base)
+		}
+		base.Mul(base, base)
+		n >>= 1
+	}
+	return result
+}
+
+// ecVRFProof is the wire format produced by vrfProve and consumed by
+// vrfVerify: the VRF intermediate point gamma, plus the Fiat-Shamir
+// challenge c and response s.
+type ecVRFProof struct {
+	GammaX, GammaY *big.Int
+	C, S           *big.Int
+}
+
+// vrfProve computes an ECVRF output (hash, proof) over input using sk's
+// curve, via hash-to-curve by try-and-increment: gamma = sk*H, and a
+// Fiat-Shamir proof c, s such that s*G - c*pk = k*G and s*H - c*gamma = k*H.
+func vrfProve(sk *ecdsa.PrivateKey, input []byte) (hash, proof []byte, err error) {
+	curve := sk.Curve
+	hx, hy, err := hashToCurve(curve, input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gammaX, gammaY := curve.ScalarMult(hx, hy, sk.D.Bytes())
+
+	k, err := rand.Int(rand.Reader, curve.Params().N)
+	if err != nil {
+		return nil, nil, err
+	}
+	kGx, kGy := curve.ScalarBaseMult(k.Bytes())
+	kHx, kHy := curve.ScalarMult(hx, hy, k.Bytes())
+
+	c := vrfChallenge(curve, hx, hy, sk.PublicKey.X, sk.PublicKey.Y, gammaX, gammaY, kGx, kGy, kHx, kHy)
+	s := new(big.Int).Mul(c, sk.D)
+	s.Add(s, k)
+	s.Mod(s, curve.Params().N)
+
+	out := sha256.Sum256(append(gammaX.Bytes(), gammaY.Bytes()...))
+	return out[:], encodeProof(&ecVRFProof{GammaX: gammaX, GammaY: gammaY, C: c, S: s}), nil
+}
+
+// vrfVerify recomputes the Fiat-Shamir challenge from
+// (gamma, s*G - c*pk, s*H - c*gamma) and checks it matches the one embedded
+// in proof, then checks hash is gamma's digest.
+func vrfVerify(pk *ecdsa.PublicKey, input, hash, proof []byte) bool {
+	curve := pk.Curve
+	p, err := decodeProof(proof)
+	if err != nil {
+		return false
+	}
+
+	wantHash := sha256.Sum256(append(p.GammaX.Bytes(), p.GammaY.Bytes()...))
+	if len(hash) != len(wantHash) {
+		return false
+	}
+	for i := range hash {
+		if hash[i] != wantHash[i] {
+			return false
+		}
+	}
+
+	hx, hy, err := hashToCurve(curve, input)
+	if err != nil {
+		return false
+	}
+
+	negC := new(big.Int).Neg(p.C)
+	negC.Mod(negC, curve.Params().N)
+
+	cPkX, cPkY := curve.ScalarMult(pk.X, pk.Y, negC.Bytes())
+	uX, uY := curve.ScalarBaseMult(p.S.Bytes())
+	uX, uY = curve.Add(uX, uY, cPkX, cPkY)
+
+	cGammaX, cGammaY := curve.ScalarMult(p.GammaX, p.GammaY, negC.Bytes())
+	vX, vY := curve.ScalarMult(hx, hy, p.S.Bytes())
+	vX, vY = curve.Add(vX, vY, cGammaX, cGammaY)
+
+	want := vrfChallenge(curve, hx, hy, pk.X, pk.Y, p.GammaX, p.GammaY, uX, uY, vX, vY)
+	return want.Cmp(p.C) == 0
+}
+
+func vrfChallenge(curve elliptic.Curve, xs ...*big.Int) *big.Int {
+	hasher := sha256.New()
+	for _, x := range xs {
+		if x == nil {
+			continue
+		}
+		hasher.Write(x.Bytes())
+	}
+	c := new(big.Int).SetBytes(hasher.Sum(nil))
+	return c.Mod(c, curve.Params().N)
+}
+
+// hashToCurve maps input onto a point on curve using try-and-increment:
+// hash input||counter until the result is a valid x-coordinate with a
+// square y.
+func hashToCurve(curve elliptic.Curve, input []byte) (x, y *big.Int, err error) {
+	params := curve.Params()
+	for counter := byte(0); counter < 255; counter++ {
+		digest := sha256.Sum256(append(input, counter))
+		x := new(big.Int).SetBytes(digest[:])
+		x.Mod(x, params.P)
+
+		if yy, ok := liftX(curve, x); ok {
+			return x, yy, nil
+		}
+	}
+	return nil, nil, errors.New("etrue: hash-to-curve exhausted try-and-increment counter")
+}
+
+// liftX solves y^2 = x^3 - 3x + b (mod p) for the given x and returns one
+// of the two roots, or ok=false if x is not on the curve.
+func liftX(curve elliptic.Curve, x *big.Int) (*big.Int, bool) {
+	params := curve.Params()
+	ySquared := new(big.Int).Mul(x, x)
+	ySquared.Mul(ySquared, x)
+
+	threeX := new(big.Int).Mul(x, big.NewInt(3))
+	ySquared.Sub(ySquared, threeX)
+	ySquared.Add(ySquared, params.B)
+	ySquared.Mod(ySquared, params.P)
+
+	y := new(big.Int).ModSqrt(ySquared, params.P)
+	if y == nil {
+		return nil, false
+	}
+	return y, true
+}
+
+func encodeProof(p *ecVRFProof) []byte {
+	parts := [][]byte{p.GammaX.Bytes(), p.GammaY.Bytes(), p.C.Bytes(), p.S.Bytes()}
+	out := make([]byte, 0, len(parts)*2+64)
+	for _, part := range parts {
+		out = append(out, byte(len(part)>>8), byte(len(part)))
+		out = append(out, part...)
+	}
+	return out
+}
+
+func decodeProof(proof []byte) (*ecVRFProof, error) {
+	fields := make([]*big.Int, 0, 4)
+	for i := 0; i < 4; i++ {
+		if len(proof) < 2 {
+			return nil, errors.New("etrue: truncated VRF proof")
+		}
+		n := int(proof[0])<<8 | int(proof[1])
+		proof = proof[2:]
+		if len(proof) < n {
+			return nil, errors.New("etrue: truncated VRF proof")
+		}
+		fields = append(fields, new(big.Int).SetBytes(proof[:n]))
+		proof = proof[n:]
+	}
+	return &ecVRFProof{GammaX: fields[0], GammaY: fields[1], C: fields[2], S: fields[3]}, nil
+}
@@ -0,0 +1,66 @@
+package etrue
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+)
+
+func deterministicKey(t *testing.T, d int64) *ecdsa.PrivateKey {
+	t.Helper()
+	curve := elliptic.P256()
+	key := new(ecdsa.PrivateKey)
+	key.Curve = curve
+	key.D = big.NewInt(d)
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(key.D.Bytes())
+	return key
+}
+
+func TestSortitionVerifiesOwnDraw(t *testing.T) {
+	sk := deterministicKey(t, 12345)
+	seed := []byte("epoch-1-seed")
+
+	hash, proof, j, selected := Sortition(sk, seed, 50, 1000, 10000, "validator")
+	if len(hash) == 0 || len(proof) == 0 {
+		t.Fatalf("Sortition returned empty hash/proof")
+	}
+
+	gotJ, ok := VerifySortition(&sk.PublicKey, seed, hash, proof, 50, 1000, 10000, "validator")
+	if !ok {
+		t.Fatalf("VerifySortition rejected a valid draw")
+	}
+	if gotJ != j {
+		t.Fatalf("VerifySortition recomputed j = %d, want %d", gotJ, j)
+	}
+	if selected != (j > 0) {
+		t.Fatalf("selected = %v inconsistent with j = %d", selected, j)
+	}
+}
+
+func TestVerifySortitionRejectsTamperedProof(t *testing.T) {
+	sk := deterministicKey(t, 99999)
+	seed := []byte("epoch-2-seed")
+
+	hash, proof, _, _ := Sortition(sk, seed, 50, 1000, 10000, "validator")
+
+	tampered := make([]byte, len(proof))
+	copy(tampered, proof)
+	tampered[len(tampered)-1] ^= 0xff
+
+	if _, ok := VerifySortition(&sk.PublicKey, seed, hash, tampered, 50, 1000, 10000, "validator"); ok {
+		t.Fatalf("VerifySortition accepted a tampered proof")
+	}
+}
+
+func TestVerifySortitionRejectsWrongKey(t *testing.T) {
+	sk := deterministicKey(t, 11111)
+	other := deterministicKey(t, 22222)
+	seed := []byte("epoch-3-seed")
+
+	hash, proof, _, _ := Sortition(sk, seed, 50, 1000, 10000, "validator")
+
+	if _, ok := VerifySortition(&other.PublicKey, seed, hash, proof, 50, 1000, 10000, "validator"); ok {
+		t.Fatalf("VerifySortition accepted a proof under the wrong public key")
+	}
+}
@@ -0,0 +1,137 @@
+package network
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/crypto"
+	"github.com/truechain/truechain-engineering-code/etrue"
+	"github.com/truechain/truechain-engineering-code/pbftserver/consensus"
+)
+
+// newAuthTestNode builds a minimal two-member committee (self + peer) with
+// real signing keys, enough to exercise signEnvelope/verifyEnvelope without
+// pulling in NewNode's transport/dispatch goroutines.
+func newAuthTestNode(t *testing.T) (node *Node, peerKey *ecdsa.PrivateKey, peerID string) {
+	t.Helper()
+
+	selfKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate self key: %v", err)
+	}
+	peerKey, err = crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate peer key: %v", err)
+	}
+	selfID := common.ToHex(crypto.FromECDSAPub(&selfKey.PublicKey))
+	peerID = common.ToHex(crypto.FromECDSAPub(&peerKey.PublicKey))
+
+	node = &Node{
+		NodeID: selfID,
+		NodeTable: map[string]string{
+			selfID: "self:0",
+			peerID: "peer:0",
+		},
+		vc:    newViewChangeState(),
+		dedup: newDedupCache(dedupCacheSize),
+	}
+	node.SetSigningKey(selfKey)
+	return node, peerKey, peerID
+}
+
+func signedEnvelope(t *testing.T, signer *ecdsa.PrivateKey, senderID, msgType string, viewID, seqID int64, payload []byte) *Envelope {
+	t.Helper()
+	env := &Envelope{MsgType: msgType, ViewID: viewID, SequenceID: seqID, SenderID: senderID, Payload: payload}
+	sig, err := etrue.Sign(envelopeDigest(env), signer)
+	if err != nil {
+		t.Fatalf("sign envelope: %v", err)
+	}
+	env.Signature = sig
+	return env
+}
+
+func TestVerifyEnvelopeAcceptsValidSignature(t *testing.T) {
+	node, peerKey, peerID := newAuthTestNode(t)
+	env := signedEnvelope(t, peerKey, peerID, "prepare", 1, 1, []byte("payload"))
+
+	if err := node.verifyEnvelope(env); err != nil {
+		t.Fatalf("verifyEnvelope: %v", err)
+	}
+}
+
+func TestVerifyEnvelopeRejectsUnknownSender(t *testing.T) {
+	node, _, _ := newAuthTestNode(t)
+	strangerKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate stranger key: %v", err)
+	}
+	strangerID := common.ToHex(crypto.FromECDSAPub(&strangerKey.PublicKey))
+	env := signedEnvelope(t, strangerKey, strangerID, "prepare", 1, 1, []byte("payload"))
+
+	if err := node.verifyEnvelope(env); err == nil {
+		t.Fatal("verifyEnvelope accepted an envelope from a non-committee sender")
+	}
+	if got := node.Metrics().DroppedUnauth; got != 1 {
+		t.Fatalf("DroppedUnauth = %d, want 1", got)
+	}
+}
+
+func TestVerifyEnvelopeRejectsBadSignature(t *testing.T) {
+	node, peerKey, peerID := newAuthTestNode(t)
+	env := signedEnvelope(t, peerKey, peerID, "prepare", 1, 1, []byte("payload"))
+	env.Payload = []byte("tampered") // invalidates the signature over envelopeDigest
+
+	if err := node.verifyEnvelope(env); err == nil {
+		t.Fatal("verifyEnvelope accepted an envelope with a tampered payload")
+	}
+	if got := node.Metrics().InvalidSig; got != 1 {
+		t.Fatalf("InvalidSig = %d, want 1", got)
+	}
+}
+
+func TestVerifyEnvelopeRejectsReplay(t *testing.T) {
+	node, peerKey, peerID := newAuthTestNode(t)
+	env := signedEnvelope(t, peerKey, peerID, "prepare", 1, 1, []byte("payload"))
+
+	if err := node.verifyEnvelope(env); err != nil {
+		t.Fatalf("first verifyEnvelope: %v", err)
+	}
+	replay := *env
+	if err := node.verifyEnvelope(&replay); err == nil {
+		t.Fatal("verifyEnvelope accepted a replayed envelope")
+	}
+	if got := node.Metrics().DroppedReplay; got != 1 {
+		t.Fatalf("DroppedReplay = %d, want 1", got)
+	}
+}
+
+func TestHandleEnvelopeRemembersVoteEnvelope(t *testing.T) {
+	node, peerKey, peerID := newAuthTestNode(t)
+	node.MsgEntrance = make(chan interface{}, 1)
+
+	vote := &consensus.VoteMsg{ViewID: 1, SequenceID: 2, Digest: "d", NodeID: peerID}
+	payload, err := json.Marshal(vote)
+	if err != nil {
+		t.Fatalf("marshal vote: %v", err)
+	}
+	env := signedEnvelope(t, peerKey, peerID, "prepare", 1, 2, payload)
+	raw, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+
+	if err := node.HandleEnvelope(raw); err != nil {
+		t.Fatalf("HandleEnvelope: %v", err)
+	}
+	<-node.MsgEntrance
+
+	key := voteKey{ViewID: 1, SeqID: 2, MsgType: "prepare", NodeID: peerID}
+	node.vc.mu.Lock()
+	_, remembered := node.vc.voteEnvelopes[key]
+	node.vc.mu.Unlock()
+	if !remembered {
+		t.Fatal("HandleEnvelope did not remember the verified vote envelope")
+	}
+}
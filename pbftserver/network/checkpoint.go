@@ -0,0 +1,300 @@
+package network
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/truechain/truechain-engineering-code/etrue/beacon"
+	"github.com/truechain/truechain-engineering-code/pbftserver/consensus"
+)
+
+// DefaultCheckpointInterval is how many committed sequences (K) elapse
+// between checkpoints, unless a Node is configured otherwise.
+const DefaultCheckpointInterval = 128
+
+// DefaultWatermarkWindow (L) bounds how far a message's SequenceID may sit
+// ahead of the low watermark (the last stable checkpoint) before routeMsg
+// drops it as out of range.
+const DefaultWatermarkWindow = 4 * DefaultCheckpointInterval
+
+// CheckpointMsg is broadcast once a replica has committed up through a
+// multiple of the checkpoint interval. Once 2f+1 replicas broadcast a
+// matching StateDigest for the same SeqID, it becomes a stable checkpoint.
+type CheckpointMsg struct {
+	SeqID       int64
+	StateDigest string
+	NodeID      string
+	Sig         []byte
+}
+
+// StableCheckpoint is the durable recovery anchor produced once a
+// CheckpointMsg reaches quorum.
+type StableCheckpoint struct {
+	SeqID       int64
+	StateDigest string
+}
+
+// CheckpointStore persists the latest stable checkpoint so a restarted
+// node can resume from it rather than replaying from genesis. Implementers
+// may back this with a file, a KV store, or (in tests) memory.
+type CheckpointStore interface {
+	SaveCheckpoint(cp *StableCheckpoint) error
+	LoadCheckpoint() (*StableCheckpoint, error)
+}
+
+// memCheckpointStore is the zero-value CheckpointStore: it keeps the
+// latest checkpoint in memory only, so a process restart loses it. It
+// exists so Node always has a working store without requiring callers to
+// wire one up.
+type memCheckpointStore struct {
+	mu  sync.Mutex
+	cur *StableCheckpoint
+}
+
+func (s *memCheckpointStore) SaveCheckpoint(cp *StableCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur = cp
+	return nil
+}
+
+func (s *memCheckpointStore) LoadCheckpoint() (*StableCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cur, nil
+}
+
+// checkpointState tracks the in-flight checkpoint protocol for one Node,
+// mirroring viewChangeState's shape.
+type checkpointState struct {
+	mu sync.Mutex
+
+	interval int64
+	window   int64
+	store    CheckpointStore
+
+	// votes[seqID][nodeID] accumulates CheckpointMsgs for a candidate
+	// checkpoint.
+	votes map[int64]map[string]*CheckpointMsg
+	// sent dedups our own checkpoint broadcast per seqID.
+	sent map[int64]bool
+
+	stable *StableCheckpoint
+}
+
+func newCheckpointState(store CheckpointStore) *checkpointState {
+	if store == nil {
+		store = &memCheckpointStore{}
+	}
+	cs := &checkpointState{
+		interval: DefaultCheckpointInterval,
+		window:   DefaultWatermarkWindow,
+		store:    store,
+		votes:    make(map[int64]map[string]*CheckpointMsg),
+		sent:     make(map[int64]bool),
+	}
+	if cp, err := store.LoadCheckpoint(); err == nil && cp != nil {
+		cs.stable = cp
+	}
+	return cs
+}
+
+// SetBeacon wires up the randomness beacon that drives primary selection
+// (see newPrimaryFor) and starts a goroutine that watches for new entries
+// so committee rotation can react to them as soon as they appear rather
+// than polling LatestRound.
+func (node *Node) SetBeacon(b beacon.Beacon) {
+	node.Beacon = b
+	go func() {
+		for entry := range b.NewEntries() {
+			LogStage(fmt.Sprintf("Beacon round %d observed, committee may rotate", entry.Round), true)
+		}
+	}()
+}
+
+// SetCheckpointStore swaps in a durable CheckpointStore, replaying any
+// checkpoint it already holds. Call it right after construction, before
+// consensus traffic starts flowing.
+func (node *Node) SetCheckpointStore(store CheckpointStore) {
+	node.cp.mu.Lock()
+	defer node.cp.mu.Unlock()
+	node.cp.store = store
+	if cp, err := store.LoadCheckpoint(); err == nil && cp != nil {
+		node.cp.stable = cp
+	}
+}
+
+// SetCheckpointInterval overrides K, the number of committed sequences
+// between checkpoints. Must be called before consensus starts.
+func (node *Node) SetCheckpointInterval(k int64) {
+	node.cp.mu.Lock()
+	defer node.cp.mu.Unlock()
+	node.cp.interval = k
+	node.cp.window = 4 * k
+}
+
+// StableCheckpoint returns the highest SequenceID with a quorum-confirmed
+// checkpoint, or 0 if none has formed yet. It is the recovery anchor the
+// view-change protocol reports in ViewChangeMsg.
+func (node *Node) StableCheckpoint() int64 {
+	node.cp.mu.Lock()
+	defer node.cp.mu.Unlock()
+	if node.cp.stable == nil {
+		return 0
+	}
+	return node.cp.stable.SeqID
+}
+
+// maybeCheckpoint is called after every successful commit; once seqID lands
+// on a checkpoint boundary it broadcasts this replica's CheckpointMsg.
+func (node *Node) maybeCheckpoint(seqID int64, stateDigest string) {
+	node.cp.mu.Lock()
+	interval := node.cp.interval
+	already := node.cp.sent[seqID]
+	node.cp.mu.Unlock()
+
+	if interval <= 0 || seqID%interval != 0 || already {
+		return
+	}
+
+	node.cp.mu.Lock()
+	node.cp.sent[seqID] = true
+	node.cp.mu.Unlock()
+
+	msg := &CheckpointMsg{
+		SeqID:       seqID,
+		StateDigest: stateDigest,
+		NodeID:      node.NodeID,
+	}
+	if node.Verify != nil {
+		msg.Sig = node.Verify.SignMsg(seqID, 0)
+	}
+
+	node.GetCheckpoint(msg)
+	node.Broadcast(msg, "/checkpoint")
+}
+
+// GetCheckpoint processes an incoming (or locally generated) CheckpointMsg
+// and, once 2f+1 replicas agree on the same (SeqID, StateDigest), makes it
+// the new stable checkpoint and prunes everything at or below it.
+func (node *Node) GetCheckpoint(msg *CheckpointMsg) error {
+	f := len(node.NodeTable) / 3
+
+	node.cp.mu.Lock()
+	bucket, ok := node.cp.votes[msg.SeqID]
+	if !ok {
+		bucket = make(map[string]*CheckpointMsg)
+		node.cp.votes[msg.SeqID] = bucket
+	}
+	bucket[msg.NodeID] = msg
+
+	matching := 0
+	for _, v := range bucket {
+		if v.StateDigest == msg.StateDigest {
+			matching++
+		}
+	}
+	alreadyStable := node.cp.stable != nil && node.cp.stable.SeqID >= msg.SeqID
+	node.cp.mu.Unlock()
+
+	if matching < 2*f+1 || alreadyStable {
+		return nil
+	}
+
+	stable := &StableCheckpoint{SeqID: msg.SeqID, StateDigest: msg.StateDigest}
+	node.cp.mu.Lock()
+	node.cp.stable = stable
+	for seqID := range node.cp.votes {
+		if seqID <= stable.SeqID {
+			delete(node.cp.votes, seqID)
+		}
+	}
+	node.cp.mu.Unlock()
+
+	if err := node.cp.store.SaveCheckpoint(stable); err != nil {
+		fmt.Println("pbftserver: persist checkpoint failed:", err)
+	}
+
+	node.pruneBelow(stable.SeqID)
+	return nil
+}
+
+// pruneBelow drops CommittedMsgs and buffered PrePrepare/Prepare/Commit
+// entries at or below seqID, since the checkpoint protocol has now
+// established that state durably.
+func (node *Node) pruneBelow(seqID int64) {
+	kept := node.CommittedMsgs[:0]
+	for _, msg := range node.CommittedMsgs {
+		if msg.SequenceID > seqID {
+			kept = append(kept, msg)
+		}
+	}
+	node.CommittedMsgs = kept
+
+	prePrepares := node.MsgBuffer.PrePrepareMsgs[:0]
+	for _, msg := range node.MsgBuffer.PrePrepareMsgs {
+		if msg.SequenceID > seqID {
+			prePrepares = append(prePrepares, msg)
+		}
+	}
+	node.MsgBuffer.PrePrepareMsgs = prePrepares
+
+	prepares := node.MsgBuffer.PrepareMsgs[:0]
+	for _, msg := range node.MsgBuffer.PrepareMsgs {
+		if msg.SequenceID > seqID {
+			prepares = append(prepares, msg)
+		}
+	}
+	node.MsgBuffer.PrepareMsgs = prepares
+
+	commits := node.MsgBuffer.CommitMsgs[:0]
+	for _, msg := range node.MsgBuffer.CommitMsgs {
+		if msg.SequenceID > seqID {
+			commits = append(commits, msg)
+		}
+	}
+	node.MsgBuffer.CommitMsgs = commits
+
+	node.vc.mu.Lock()
+	for s := range node.vc.preparedProofs {
+		if s <= seqID {
+			delete(node.vc.preparedProofs, s)
+		}
+	}
+	for k := range node.vc.voteEnvelopes {
+		if k.SeqID <= seqID {
+			delete(node.vc.voteEnvelopes, k)
+		}
+	}
+	node.vc.mu.Unlock()
+}
+
+// inWatermarkWindow reports whether seqID falls within [low, low+L], the
+// high/low watermark window routeMsg enforces so a node can't be forced to
+// buffer unboundedly many future messages.
+func (node *Node) inWatermarkWindow(seqID int64) bool {
+	node.cp.mu.Lock()
+	low := int64(0)
+	if node.cp.stable != nil {
+		low = node.cp.stable.SeqID
+	}
+	window := node.cp.window
+	node.cp.mu.Unlock()
+
+	return seqID >= low && seqID <= low+window
+}
+
+// seqIDOf extracts the SequenceID routeMsg should watermark-check for the
+// consensus message types that carry one.
+func seqIDOf(msg interface{}) (int64, bool) {
+	switch m := msg.(type) {
+	case *consensus.RequestMsg:
+		return m.SequenceID, true
+	case *consensus.PrePrepareMsg:
+		return m.SequenceID, true
+	case *consensus.VoteMsg:
+		return m.SequenceID, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,121 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/truechain/truechain-engineering-code/pbftserver/consensus"
+)
+
+// newCheckpointTestNode builds a 4-member committee (f=1, quorum=3) Node
+// with just enough wiring to drive the checkpoint protocol directly.
+func newCheckpointTestNode(nodeID string) *Node {
+	nodeTable := map[string]string{
+		"n0": "a", "n1": "b", "n2": "c", "n3": "d",
+	}
+	return &Node{
+		NodeID:        nodeID,
+		NodeTable:     nodeTable,
+		CommittedMsgs: make([]*consensus.RequestMsg, 0),
+		MsgBuffer: &MsgBuffer{
+			ReqMsgs:        make([]*consensus.RequestMsg, 0),
+			PrePrepareMsgs: make([]*consensus.PrePrepareMsg, 0),
+			PrepareMsgs:    make([]*consensus.VoteMsg, 0),
+			CommitMsgs:     make([]*consensus.VoteMsg, 0),
+		},
+		vc: newViewChangeState(),
+		cp: newCheckpointState(nil),
+	}
+}
+
+func TestGetCheckpointFormsStableOnQuorum(t *testing.T) {
+	node := newCheckpointTestNode("n0")
+
+	for i, id := range []string{"n0", "n1", "n2"} {
+		err := node.GetCheckpoint(&CheckpointMsg{SeqID: 128, StateDigest: "d", NodeID: id})
+		if err != nil {
+			t.Fatalf("GetCheckpoint %d: %v", i, err)
+		}
+	}
+
+	if got := node.StableCheckpoint(); got != 128 {
+		t.Fatalf("StableCheckpoint = %d, want 128", got)
+	}
+}
+
+func TestGetCheckpointIgnoresNonMatchingDigests(t *testing.T) {
+	node := newCheckpointTestNode("n0")
+
+	node.GetCheckpoint(&CheckpointMsg{SeqID: 128, StateDigest: "d", NodeID: "n0"})
+	node.GetCheckpoint(&CheckpointMsg{SeqID: 128, StateDigest: "d", NodeID: "n1"})
+	node.GetCheckpoint(&CheckpointMsg{SeqID: 128, StateDigest: "other", NodeID: "n2"})
+
+	if got := node.StableCheckpoint(); got != 0 {
+		t.Fatalf("StableCheckpoint = %d, want 0 (no quorum on a single digest yet)", got)
+	}
+}
+
+func TestPruneBelowDropsOldEntries(t *testing.T) {
+	node := newCheckpointTestNode("n0")
+
+	node.CommittedMsgs = append(node.CommittedMsgs,
+		&consensus.RequestMsg{SequenceID: 1},
+		&consensus.RequestMsg{SequenceID: 2},
+		&consensus.RequestMsg{SequenceID: 3},
+	)
+	node.MsgBuffer.PrePrepareMsgs = append(node.MsgBuffer.PrePrepareMsgs,
+		&consensus.PrePrepareMsg{SequenceID: 1},
+		&consensus.PrePrepareMsg{SequenceID: 3},
+	)
+	node.vc.preparedProofs[1] = &PreparedProof{SequenceID: 1}
+	node.vc.preparedProofs[3] = &PreparedProof{SequenceID: 3}
+	node.vc.voteEnvelopes[voteKey{SeqID: 1, ViewID: 0, MsgType: "prepare", NodeID: "n1"}] = &Envelope{}
+	node.vc.voteEnvelopes[voteKey{SeqID: 3, ViewID: 0, MsgType: "prepare", NodeID: "n1"}] = &Envelope{}
+
+	node.pruneBelow(2)
+
+	if len(node.CommittedMsgs) != 1 || node.CommittedMsgs[0].SequenceID != 3 {
+		t.Fatalf("CommittedMsgs after prune = %v, want only sequence 3", node.CommittedMsgs)
+	}
+	if len(node.MsgBuffer.PrePrepareMsgs) != 1 || node.MsgBuffer.PrePrepareMsgs[0].SequenceID != 3 {
+		t.Fatalf("PrePrepareMsgs after prune = %v, want only sequence 3", node.MsgBuffer.PrePrepareMsgs)
+	}
+	if _, ok := node.vc.preparedProofs[1]; ok {
+		t.Fatal("preparedProofs still holds sequence 1 after pruning below 2")
+	}
+	if _, ok := node.vc.preparedProofs[3]; !ok {
+		t.Fatal("preparedProofs lost sequence 3, which is above the prune threshold")
+	}
+	if _, ok := node.vc.voteEnvelopes[voteKey{SeqID: 1, ViewID: 0, MsgType: "prepare", NodeID: "n1"}]; ok {
+		t.Fatal("voteEnvelopes still holds sequence 1 after pruning below 2")
+	}
+	if _, ok := node.vc.voteEnvelopes[voteKey{SeqID: 3, ViewID: 0, MsgType: "prepare", NodeID: "n1"}]; !ok {
+		t.Fatal("voteEnvelopes lost sequence 3, which is above the prune threshold")
+	}
+}
+
+func TestInWatermarkWindow(t *testing.T) {
+	node := newCheckpointTestNode("n0")
+	node.SetCheckpointInterval(128)
+
+	for i, id := range []string{"n0", "n1", "n2"} {
+		if err := node.GetCheckpoint(&CheckpointMsg{SeqID: 128, StateDigest: "d", NodeID: id}); err != nil {
+			t.Fatalf("GetCheckpoint %d: %v", i, err)
+		}
+	}
+
+	cases := []struct {
+		seqID int64
+		want  bool
+	}{
+		{0, false},   // below the low watermark
+		{128, true},  // at the low watermark
+		{256, true},  // within the window (low + L)
+		{640, true},  // at low + L (4*128)
+		{641, false}, // past low + L
+	}
+	for _, c := range cases {
+		if got := node.inWatermarkWindow(c.seqID); got != c.want {
+			t.Errorf("inWatermarkWindow(%d) = %v, want %v", c.seqID, got, c.want)
+		}
+	}
+}
@@ -1,12 +1,13 @@
 package network
 
 import (
-	"encoding/json"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"github.com/truechain/truechain-engineering-code/common"
 	"github.com/truechain/truechain-engineering-code/core/types"
 	"github.com/truechain/truechain-engineering-code/crypto"
+	"github.com/truechain/truechain-engineering-code/etrue/beacon"
 	"github.com/truechain/truechain-engineering-code/pbftserver/consensus"
 	"time"
 )
@@ -22,6 +23,26 @@ type Node struct {
 	MsgDelivery   chan interface{}
 	Alarm         chan bool
 	Verify        consensus.ConsensusVerify
+	Transport     Transport
+
+	// vc holds the view-change protocol's bookkeeping (see viewchange.go).
+	vc *viewChangeState
+	// ViewChanged is notified with the new View.ID every time GetNewView
+	// installs a new primary.
+	ViewChanged chan int64
+
+	// cp holds the checkpoint protocol's bookkeeping (see checkpoint.go).
+	cp *checkpointState
+
+	// Beacon, when set, drives primary selection: the primary for view v
+	// is chosen from beacon randomness instead of a round-robin order, so
+	// an adversary can't predict or bias who leads an upcoming view.
+	Beacon beacon.Beacon
+
+	// SigningKey signs every outgoing Envelope; see SetSigningKey.
+	SigningKey  *ecdsa.PrivateKey
+	authMetrics AuthMetrics
+	dedup       *dedupCache
 }
 
 type MsgBuffer struct {
@@ -38,7 +59,16 @@ type View struct {
 
 const ResolvingTimeDuration = time.Millisecond * 1000 // 1 second.
 
+// NewNode builds a Node that talks to its committee over the legacy HTTP
+// transport. Use NewNodeWithTransport to opt into the pubsub transport.
 func NewNode(nodeID string, verify consensus.ConsensusVerify, addrs []*types.CommitteeNode) *Node {
+	return NewNodeWithTransport(nodeID, verify, addrs, TransportHTTP)
+}
+
+// NewNodeWithTransport is like NewNode but lets the caller pick which
+// Transport carries PBFT messages. TransportHTTP reproduces the original
+// per-peer HTTP fan-out; TransportPubsub rides a libp2p-gossipsub mesh.
+func NewNodeWithTransport(nodeID string, verify consensus.ConsensusVerify, addrs []*types.CommitteeNode, kind TransportKind) *Node {
 	const viewID = 10000000000 // temporary.
 	if len(addrs) <= 0 {
 		return nil
@@ -72,6 +102,19 @@ func NewNode(nodeID string, verify consensus.ConsensusVerify, addrs []*types.Com
 		MsgEntrance: make(chan interface{}),
 		MsgDelivery: make(chan interface{}),
 		Alarm:       make(chan bool),
+		ViewChanged: make(chan int64, 1),
+	}
+	node.vc = newViewChangeState()
+	node.cp = newCheckpointState(nil)
+	node.dedup = newDedupCache(dedupCacheSize)
+	node.Transport = newTransport(kind, node)
+
+	// Pubsub observers feed MsgEntrance directly; the HTTP transport keeps
+	// delivering through the existing Get* handlers instead.
+	for _, topic := range topics {
+		if err := node.Transport.Subscribe(topic, node.MsgEntrance); err != nil {
+			fmt.Println("pbftserver: subscribe", topic, "failed:", err)
+		}
 	}
 
 	// Start message dispatcher
@@ -86,28 +129,12 @@ func NewNode(nodeID string, verify consensus.ConsensusVerify, addrs []*types.Com
 	return node
 }
 
+// Broadcast fans msg out to the rest of the committee over node.Transport.
+// path keeps the legacy "/preprepare"-style spelling used by callers and
+// the HTTP handlers; it is translated to a bare topic name for transports
+// that don't route by URL path.
 func (node *Node) Broadcast(msg interface{}, path string) map[string]error {
-	errorMap := make(map[string]error)
-
-	for nodeID, url := range node.NodeTable {
-		if nodeID == node.NodeID {
-			continue
-		}
-
-		jsonMsg, err := json.Marshal(msg)
-		if err != nil {
-			errorMap[nodeID] = err
-			continue
-		}
-
-		send(url+path, jsonMsg)
-	}
-
-	if len(errorMap) == 0 {
-		return nil
-	} else {
-		return errorMap
-	}
+	return node.Transport.Broadcast(msg, pathToTopic(path))
 }
 func (node *Node) handleResult(msg *consensus.ReplyMsg) {
 	var res uint = 0
@@ -209,6 +236,16 @@ func (node *Node) GetPrepare(prepareMsg *consensus.VoteMsg) error {
 	}
 
 	if commitMsg != nil {
+		// Reaching here means 2f+1 matching prepares were collected, i.e.
+		// this replica is now Prepared for the sequence; keep the evidence
+		// around so a future view change can safely re-propose it.
+		node.recordPreparedProof(&consensus.PrePrepareMsg{
+			ViewID:     prepareMsg.ViewID,
+			SequenceID: prepareMsg.SequenceID,
+			Digest:     prepareMsg.Digest,
+			RequestMsg: node.CurrentState.MsgLogs.ReqMsg,
+		}, node.MsgBuffer.PrepareMsgs)
+
 		// Attach node ID to the message
 		commitMsg.NodeID = node.NodeID
 		res := node.Verify.CheckMsg(node.CurrentState.MsgLogs.ReqMsg)
@@ -245,9 +282,16 @@ func (node *Node) GetCommit(commitMsg *consensus.VoteMsg) error {
 		// Save the last version of committed messages to node.
 		node.CommittedMsgs = append(node.CommittedMsgs, committedMsg)
 
+		node.vc.mu.Lock()
+		delete(node.vc.stuckSince, committedMsg.SequenceID)
+		delete(node.vc.attempts, committedMsg.SequenceID)
+		node.vc.mu.Unlock()
+
 		LogStage("Commit", true)
 		node.Reply(replyMsg)
 		LogStage("Reply", true)
+
+		node.maybeCheckpoint(committedMsg.SequenceID, commitMsg.Digest)
 	}
 	fmt.Println("node commit end")
 	return nil
@@ -307,7 +351,23 @@ func printLog(state *Node, name string) {
 }
 
 func (node *Node) routeMsg(msg interface{}) []error {
+	if seqID, ok := seqIDOf(msg); ok && !node.inWatermarkWindow(seqID) {
+		return []error{fmt.Errorf("pbftserver: dropping message for sequence %d outside watermark window", seqID)}
+	}
+
 	switch msg.(type) {
+	case *ViewChangeMsg:
+		if err := node.GetViewChange(msg.(*ViewChangeMsg)); err != nil {
+			return []error{err}
+		}
+	case *NewViewMsg:
+		if err := node.GetNewView(msg.(*NewViewMsg)); err != nil {
+			return []error{err}
+		}
+	case *CheckpointMsg:
+		if err := node.GetCheckpoint(msg.(*CheckpointMsg)); err != nil {
+			return []error{err}
+		}
 	case *consensus.RequestMsg:
 		if node.CurrentState == nil {
 			printLog(node, "RequestMsg")
@@ -394,6 +454,13 @@ func (node *Node) routeMsg(msg interface{}) []error {
 }
 
 func (node *Node) routeMsgWhenAlarmed() []error {
+	if node.CurrentState != nil && node.CurrentState.MsgLogs.ReqMsg != nil {
+		// A consensus round is still outstanding on every alarm tick;
+		// escalate towards a view change once this sequence's backoff
+		// window has elapsed.
+		node.maybeEscalateViewChange(node.CurrentState.MsgLogs.ReqMsg.SequenceID)
+	}
+
 	if node.CurrentState == nil {
 		// Check ReqMsgs, send them.
 		if len(node.MsgBuffer.ReqMsgs) != 0 {
@@ -0,0 +1,158 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/truechain/truechain-engineering-code/pbftserver/consensus"
+)
+
+// TransportKind selects which Transport implementation NewNode wires up.
+// HTTP remains the default so existing deployments keep working unchanged.
+type TransportKind string
+
+const (
+	TransportHTTP   TransportKind = "http"
+	TransportPubsub TransportKind = "pubsub"
+)
+
+// Envelope is the stable wire format every PBFT message travels in,
+// regardless of which Transport carries it. Payload holds the message of
+// MsgType's topic (see topicMessage), CBOR-encoded by the gossip transport
+// and JSON-encoded by the HTTP transport;
+// MsgType is the topic it was published on so the receiving end knows how
+// to decode Payload before handing it to routeMsg.
+type Envelope struct {
+	MsgType    string
+	ViewID     int64
+	SequenceID int64
+	SenderID   string
+	Payload    []byte
+	Signature  []byte
+}
+
+// Transport abstracts how PBFT messages are fanned out to the rest of the
+// committee and how inbound messages are delivered back into the node.
+// The original implementation posted JSON over per-peer HTTP endpoints in a
+// blocking loop; Transport lets that be swapped for a pubsub-based
+// implementation without touching the consensus state machine.
+type Transport interface {
+	// Broadcast publishes msg on topic ("preprepare", "prepare", "commit",
+	// "reply") to every other member of the committee. It returns nil once
+	// the message has been handed off, or a map of errors for failures to
+	// encode, sign or publish it: the pubsub transport reports those against
+	// the empty nodeID, the HTTP transport against this node's own NodeID.
+	// Neither reports whether an individual peer received the message.
+	Broadcast(msg interface{}, topic string) map[string]error
+
+	// Subscribe registers this node to receive Envelopes published on
+	// topic. Received envelopes are decoded and pushed onto entrance for
+	// routeMsg to process, mirroring what the HTTP handlers used to do.
+	Subscribe(topic string, entrance chan<- interface{}) error
+
+	// Close releases any transport resources (sockets, subscriptions...).
+	Close() error
+}
+
+// topics lists every PBFT message topic a transport must support.
+var topics = []string{"preprepare", "prepare", "commit", "reply", "viewchange", "newview", "checkpoint"}
+
+// pathToTopic maps the legacy HTTP endpoint paths to their pubsub topic
+// names so callers can keep passing "/preprepare" style paths to Broadcast.
+func pathToTopic(path string) string {
+	for _, topic := range topics {
+		if path == "/"+topic {
+			return topic
+		}
+	}
+	return path
+}
+
+// topicMessage returns a fresh, empty value of the concrete consensus
+// message type topic carries, for an unmarshaller to decode into.
+func topicMessage(topic string) (interface{}, error) {
+	switch topic {
+	case "preprepare":
+		return new(consensus.PrePrepareMsg), nil
+	case "prepare", "commit":
+		return new(consensus.VoteMsg), nil
+	case "reply":
+		return new(consensus.ReplyMsg), nil
+	case "viewchange":
+		return new(ViewChangeMsg), nil
+	case "newview":
+		return new(NewViewMsg), nil
+	case "checkpoint":
+		return new(CheckpointMsg), nil
+	default:
+		return nil, fmt.Errorf("pbftserver: unknown topic %q", topic)
+	}
+}
+
+// decodeTopicPayload decodes an Envelope's CBOR payload into the concrete
+// consensus message type that topic carries, so the result can be pushed
+// onto MsgEntrance exactly like the HTTP handlers used to deliver it. Used
+// by the gossip transport, which encodes everything as CBOR.
+func decodeTopicPayload(topic string, payload []byte) (interface{}, error) {
+	msg, err := topicMessage(topic)
+	if err != nil {
+		return nil, err
+	}
+	if err := cbor.Unmarshal(payload, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// decodeTopicPayloadJSON is decodeTopicPayload's JSON counterpart, used by
+// the HTTP transport's Envelopes.
+func decodeTopicPayloadJSON(topic string, payload []byte) (interface{}, error) {
+	msg, err := topicMessage(topic)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(payload, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// envelopeIdentity extracts the (ViewID, SequenceID) pair msg carries, if
+// any, so Broadcast can stamp it onto the outgoing Envelope: that pair is
+// half of the replay-protection key verifyEnvelope checks on the way in.
+func envelopeIdentity(msg interface{}) (viewID, seqID int64) {
+	switch m := msg.(type) {
+	case *consensus.PrePrepareMsg:
+		return m.ViewID, m.SequenceID
+	case *consensus.VoteMsg:
+		return m.ViewID, m.SequenceID
+	case *consensus.ReplyMsg:
+		return m.ViewID, 0
+	case *ViewChangeMsg:
+		return m.NewViewID, 0
+	case *NewViewMsg:
+		return m.ViewID, 0
+	case *CheckpointMsg:
+		return 0, m.SeqID
+	default:
+		return 0, 0
+	}
+}
+
+// newTransport builds the Transport selected by kind. An unrecognised kind
+// falls back to HTTP so a bad config value degrades gracefully instead of
+// leaving the node unable to communicate.
+func newTransport(kind TransportKind, node *Node) Transport {
+	switch kind {
+	case TransportPubsub:
+		t, err := newGossipTransport(node)
+		if err != nil {
+			fmt.Println("pbftserver: falling back to HTTP transport:", err)
+			return newHTTPTransport(node)
+		}
+		return t
+	default:
+		return newHTTPTransport(node)
+	}
+}
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/host"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+// gossipTransport carries PBFT messages over a libp2p-gossipsub mesh, one
+// topic per message type ("preprepare", "prepare", "commit", "reply"). This
+// replaces the O(N) synchronous HTTP fan-out with a single publish per
+// message and lets non-committee observers subscribe and follow consensus
+// without being dialed individually.
+type gossipTransport struct {
+	node *Node
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	host host.Host
+	ps   *pubsub.PubSub
+
+	mu     sync.Mutex
+	topics map[string]*pubsub.Topic
+}
+
+func newGossipTransport(node *Node) (*gossipTransport, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h, err := libp2p.New(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("pbftserver: create libp2p host: %v", err)
+	}
+
+	ps, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("pbftserver: create gossipsub router: %v", err)
+	}
+
+	return &gossipTransport{
+		node:   node,
+		ctx:    ctx,
+		cancel: cancel,
+		host:   h,
+		ps:     ps,
+		topics: make(map[string]*pubsub.Topic),
+	}, nil
+}
+
+// joinTopic returns the pubsub.Topic handle for name, joining it the first
+// time it is needed.
+func (t *gossipTransport) joinTopic(name string) (*pubsub.Topic, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if topic, ok := t.topics[name]; ok {
+		return topic, nil
+	}
+	topic, err := t.ps.Join(name)
+	if err != nil {
+		return nil, err
+	}
+	t.topics[name] = topic
+	return topic, nil
+}
+
+func (t *gossipTransport) Broadcast(msg interface{}, topicName string) map[string]error {
+	payload, err := cbor.Marshal(msg)
+	if err != nil {
+		return map[string]error{"": err}
+	}
+
+	viewID, seqID := envelopeIdentity(msg)
+	env := &Envelope{MsgType: topicName, ViewID: viewID, SequenceID: seqID, Payload: payload}
+	if err := t.node.signEnvelope(env); err != nil {
+		return map[string]error{"": err}
+	}
+
+	raw, err := cbor.Marshal(env)
+	if err != nil {
+		return map[string]error{"": err}
+	}
+
+	topic, err := t.joinTopic(topicName)
+	if err != nil {
+		return map[string]error{"": err}
+	}
+	if err := topic.Publish(t.ctx, raw); err != nil {
+		return map[string]error{"": err}
+	}
+	return nil
+}
+
+// Subscribe joins topic and hands every received Envelope's decoded payload
+// to entrance, mirroring the shape routeMsg already expects from the HTTP
+// handlers.
+func (t *gossipTransport) Subscribe(topicName string, entrance chan<- interface{}) error {
+	topic, err := t.joinTopic(topicName)
+	if err != nil {
+		return err
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			msg, err := sub.Next(t.ctx)
+			if err != nil {
+				return // subscription cancelled or host closed
+			}
+			if msg.ReceivedFrom == t.host.ID() {
+				continue // ignore our own publish, gossipsub loops it back
+			}
+
+			var env Envelope
+			if err := cbor.Unmarshal(msg.Data, &env); err != nil {
+				fmt.Println("pbftserver: discarding malformed envelope:", err)
+				continue
+			}
+			if err := t.node.verifyEnvelope(&env); err != nil {
+				fmt.Println("pbftserver: discarding unauthenticated envelope:", err)
+				continue
+			}
+			t.node.rememberVoteEnvelope(&env)
+
+			decoded, err := decodeTopicPayload(env.MsgType, env.Payload)
+			if err != nil {
+				fmt.Println("pbftserver: discarding undecodable envelope:", err)
+				continue
+			}
+			entrance <- decoded
+		}
+	}()
+
+	return nil
+}
+
+func (t *gossipTransport) Close() error {
+	t.cancel()
+	return t.host.Close()
+}
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/truechain/truechain-engineering-code/pbftserver/consensus"
+)
+
+func TestPathToTopic(t *testing.T) {
+	cases := map[string]string{
+		"/preprepare":  "preprepare",
+		"/prepare":     "prepare",
+		"/commit":      "commit",
+		"/reply":       "reply",
+		"/viewchange":  "viewchange",
+		"/newview":     "newview",
+		"/checkpoint":  "checkpoint",
+		"/unknownpath": "/unknownpath", // unrecognised paths pass through unchanged
+	}
+	for path, want := range cases {
+		if got := pathToTopic(path); got != want {
+			t.Errorf("pathToTopic(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestEnvelopeIdentity(t *testing.T) {
+	cases := []struct {
+		name           string
+		msg            interface{}
+		wantView, want int64
+	}{
+		{"preprepare", &consensus.PrePrepareMsg{ViewID: 1, SequenceID: 2}, 1, 2},
+		{"prepare", &consensus.VoteMsg{ViewID: 3, SequenceID: 4}, 3, 4},
+		{"reply", &consensus.ReplyMsg{ViewID: 5}, 5, 0},
+		{"viewchange", &ViewChangeMsg{NewViewID: 6}, 6, 0},
+		{"newview", &NewViewMsg{ViewID: 7}, 7, 0},
+		{"checkpoint", &CheckpointMsg{SeqID: 8}, 0, 8},
+		{"unknown", struct{}{}, 0, 0},
+	}
+	for _, c := range cases {
+		viewID, seqID := envelopeIdentity(c.msg)
+		if viewID != c.wantView || seqID != c.want {
+			t.Errorf("%s: envelopeIdentity = (%d, %d), want (%d, %d)", c.name, viewID, seqID, c.wantView, c.want)
+		}
+	}
+}
+
+// Tests that every topic round-trips a payload through both the CBOR
+// (gossip) and JSON (HTTP) encodings decodeTopicPayload/decodeTopicPayloadJSON
+// use, each decoded by its own transport's decoder.
+func TestDecodeTopicPayloadRoundTrip(t *testing.T) {
+	for _, topic := range topics {
+		msg, err := topicMessage(topic)
+		if err != nil {
+			t.Fatalf("topicMessage(%q): %v", topic, err)
+		}
+
+		cborPayload, err := cbor.Marshal(msg)
+		if err != nil {
+			t.Fatalf("cbor marshal %q: %v", topic, err)
+		}
+		if _, err := decodeTopicPayload(topic, cborPayload); err != nil {
+			t.Errorf("decodeTopicPayload(%q): %v", topic, err)
+		}
+
+		jsonPayload, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("json marshal %q: %v", topic, err)
+		}
+		if _, err := decodeTopicPayloadJSON(topic, jsonPayload); err != nil {
+			t.Errorf("decodeTopicPayloadJSON(%q): %v", topic, err)
+		}
+	}
+}
+
+func TestTopicMessageUnknownTopic(t *testing.T) {
+	if _, err := topicMessage("bogus"); err == nil {
+		t.Fatal("topicMessage(\"bogus\") should have failed")
+	}
+}
+
+func TestNewTransportFallsBackToHTTPOnBadKind(t *testing.T) {
+	node := &Node{NodeID: "self", NodeTable: map[string]string{"self": "addr"}}
+	transport := newTransport(TransportKind("bogus"), node)
+	if _, ok := transport.(*httpTransport); !ok {
+		t.Fatalf("newTransport with unknown kind = %T, want *httpTransport", transport)
+	}
+}
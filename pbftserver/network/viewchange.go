@@ -0,0 +1,439 @@
+package network
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/crypto"
+	"github.com/truechain/truechain-engineering-code/etrue"
+	"github.com/truechain/truechain-engineering-code/pbftserver/consensus"
+)
+
+// PreparedProof is the evidence a replica carries that it reached the
+// Prepared stage for a given sequence: the original pre-prepare plus the
+// verified Envelopes of the matching prepare votes. Prepares carries whole
+// Envelopes rather than bare VoteMsgs so a new primary can independently
+// check who actually signed each vote (see validatePreparedProof) instead
+// of trusting a VoteMsg.NodeID that whoever is relaying this proof could
+// have forged.
+type PreparedProof struct {
+	SequenceID int64
+	PrePrepare *consensus.PrePrepareMsg
+	Prepares   []*Envelope
+}
+
+// voteKey identifies a single cast prepare/commit vote, so its verified
+// Envelope can be looked up again when a PreparedProof is assembled.
+type voteKey struct {
+	ViewID, SeqID int64
+	MsgType       string
+	NodeID        string
+}
+
+func voteKeyOf(env *Envelope) voteKey {
+	return voteKey{ViewID: env.ViewID, SeqID: env.SequenceID, MsgType: env.MsgType, NodeID: env.SenderID}
+}
+
+// ViewChangeMsg is broadcast by a replica that believes the current
+// primary has stalled. It carries every PreparedProof the replica holds so
+// the next primary can safely resume rather than replay from genesis.
+type ViewChangeMsg struct {
+	NewViewID            int64
+	NodeID               string
+	LastStableCheckpoint int64
+	PreparedProofs       []*PreparedProof
+}
+
+// NewViewMsg is broadcast by the newly elected primary once it has
+// collected 2f+1 ViewChangeMsgs. It justifies the new view with the
+// ViewChangeMsgs it relied on and re-proposes a PrePrepare for every
+// sequence any replica had already prepared.
+type NewViewMsg struct {
+	ViewID      int64
+	NodeID      string
+	ViewChanges []*ViewChangeMsg
+	PrePrepares []*consensus.PrePrepareMsg
+}
+
+// viewChangeState tracks the in-flight view-change protocol for one Node.
+// It is embedded into Node rather than being its own exported type because
+// it is only ever driven by Node's own goroutines.
+type viewChangeState struct {
+	mu sync.Mutex
+
+	// stuckSince and attempts track, per SequenceID, when the sequence was
+	// first seen outstanding and how many view changes it has escalated to,
+	// so escalation uses exponential backoff instead of view-changing on
+	// every single alarm tick.
+	stuckSince map[int64]time.Time
+	attempts   map[int64]int
+
+	// preparedProofs holds the most recent PreparedProof this replica
+	// observed for each sequence, used both to answer view-change requests
+	// and to build this replica's own ViewChangeMsg.
+	preparedProofs map[int64]*PreparedProof
+
+	// voteEnvelopes remembers the verified Envelope each Prepare/Commit
+	// vote arrived in, so recordPreparedProof can attach independently
+	// verifiable evidence instead of a bare VoteMsg.
+	voteEnvelopes map[voteKey]*Envelope
+
+	// votes[newView][nodeID] accumulates ViewChangeMsgs for a candidate view.
+	votes map[int64]map[string]*ViewChangeMsg
+
+	// sentViewChange / sentNewView dedup our own broadcasts per view.
+	sentViewChange map[int64]bool
+	sentNewView    map[int64]bool
+}
+
+func newViewChangeState() *viewChangeState {
+	return &viewChangeState{
+		stuckSince:     make(map[int64]time.Time),
+		attempts:       make(map[int64]int),
+		preparedProofs: make(map[int64]*PreparedProof),
+		voteEnvelopes:  make(map[voteKey]*Envelope),
+		votes:          make(map[int64]map[string]*ViewChangeMsg),
+		sentViewChange: make(map[int64]bool),
+		sentNewView:    make(map[int64]bool),
+	}
+}
+
+const (
+	viewChangeBaseBackoff = ResolvingTimeDuration
+	viewChangeMaxBackoff  = ResolvingTimeDuration * 16
+)
+
+// CurrentView returns the view this node currently believes is active.
+func (node *Node) CurrentView() int64 {
+	node.vc.mu.Lock()
+	defer node.vc.mu.Unlock()
+	return node.View.ID
+}
+
+// recordPreparedProof is called once a sequence reaches the Prepared stage
+// so the view-change protocol has justification to re-propose it if this
+// replica is later asked to vouch for a new primary. Only prepares this
+// replica holds a verified Envelope for (see rememberVoteEnvelope) are kept
+// as evidence; a vote this replica never itself verified can't be vouched
+// for.
+func (node *Node) recordPreparedProof(prePrepare *consensus.PrePrepareMsg, prepares []*consensus.VoteMsg) {
+	node.vc.mu.Lock()
+	defer node.vc.mu.Unlock()
+
+	evidence := make([]*Envelope, 0, len(prepares))
+	for _, v := range prepares {
+		key := voteKey{ViewID: v.ViewID, SeqID: v.SequenceID, MsgType: "prepare", NodeID: v.NodeID}
+		if env, ok := node.vc.voteEnvelopes[key]; ok {
+			evidence = append(evidence, env)
+		}
+	}
+
+	node.vc.preparedProofs[prePrepare.SequenceID] = &PreparedProof{
+		SequenceID: prePrepare.SequenceID,
+		PrePrepare: prePrepare,
+		Prepares:   evidence,
+	}
+}
+
+// rememberVoteEnvelope retains a copy of env, once verifyEnvelope has
+// authenticated it, so a later PreparedProof can cite it as evidence that
+// env.SenderID really did cast this prepare/commit vote. Only prepare and
+// commit envelopes are worth keeping for this purpose.
+func (node *Node) rememberVoteEnvelope(env *Envelope) {
+	if env.MsgType != "prepare" && env.MsgType != "commit" {
+		return
+	}
+	cp := *env
+	node.vc.mu.Lock()
+	node.vc.voteEnvelopes[voteKeyOf(env)] = &cp
+	node.vc.mu.Unlock()
+}
+
+// decodeVotePayload decodes an Envelope's CBOR- or JSON-encoded payload (the
+// gossip and HTTP transports use one each) into a VoteMsg.
+func decodeVotePayload(payload []byte) (*consensus.VoteMsg, error) {
+	vote := new(consensus.VoteMsg)
+	if err := cbor.Unmarshal(payload, vote); err == nil {
+		return vote, nil
+	}
+	if err := json.Unmarshal(payload, vote); err != nil {
+		return nil, fmt.Errorf("pbftserver: undecodable vote payload: %v", err)
+	}
+	return vote, nil
+}
+
+// validatePreparedProof reports whether proof carries at least 2f+1
+// distinct, validly-signed prepare votes agreeing with PrePrepare's digest
+// for its SequenceID — the quorum a replica could only have reached
+// Prepared with. Without this check, a single Byzantine replica could embed
+// a fabricated PreparedProof (bogus votes attributed to replicas that never
+// cast them) inside its own validly-signed ViewChangeMsg, and the new
+// primary would re-propose and commit whatever value it claims.
+func (node *Node) validatePreparedProof(proof *PreparedProof, f int) bool {
+	if proof == nil || proof.PrePrepare == nil {
+		return false
+	}
+
+	signers := make(map[string]bool)
+	for _, env := range proof.Prepares {
+		if env.MsgType != "prepare" || env.SequenceID != proof.SequenceID {
+			continue
+		}
+		if _, ok := node.NodeTable[env.SenderID]; !ok {
+			continue
+		}
+		pubkey, err := crypto.UnmarshalPubkey(common.FromHex(env.SenderID))
+		if err != nil || !etrue.Verify(envelopeDigest(env), env.Signature, pubkey) {
+			continue
+		}
+		vote, err := decodeVotePayload(env.Payload)
+		if err != nil || vote.Digest != proof.PrePrepare.Digest || vote.ViewID != proof.PrePrepare.ViewID {
+			continue
+		}
+		signers[env.SenderID] = true
+	}
+	return len(signers) >= 2*f+1
+}
+
+// maybeEscalateViewChange is called on every alarm tick while a consensus
+// round for seqID is outstanding. It escalates to a view change only once
+// the per-sequence exponential backoff has elapsed, so a single slow round
+// doesn't trigger a storm of view changes.
+func (node *Node) maybeEscalateViewChange(seqID int64) {
+	node.vc.mu.Lock()
+	first, seen := node.vc.stuckSince[seqID]
+	if !seen {
+		node.vc.stuckSince[seqID] = time.Now()
+		node.vc.mu.Unlock()
+		return
+	}
+
+	attempt := node.vc.attempts[seqID]
+	backoff := viewChangeBaseBackoff * time.Duration(1<<uint(attempt))
+	if backoff > viewChangeMaxBackoff {
+		backoff = viewChangeMaxBackoff
+	}
+	if time.Since(first) < backoff {
+		node.vc.mu.Unlock()
+		return
+	}
+	node.vc.attempts[seqID] = attempt + 1
+	node.vc.mu.Unlock()
+
+	node.beginViewChange()
+}
+
+// beginViewChange broadcasts this replica's ViewChangeMsg for the next
+// view, carrying every PreparedProof it currently holds.
+func (node *Node) beginViewChange() {
+	newView := node.CurrentView() + 1
+
+	node.vc.mu.Lock()
+	if node.vc.sentViewChange[newView] {
+		node.vc.mu.Unlock()
+		return
+	}
+	node.vc.sentViewChange[newView] = true
+
+	proofs := make([]*PreparedProof, 0, len(node.vc.preparedProofs))
+	for _, proof := range node.vc.preparedProofs {
+		proofs = append(proofs, proof)
+	}
+	node.vc.mu.Unlock()
+
+	msg := &ViewChangeMsg{
+		NewViewID:            newView,
+		NodeID:               node.NodeID,
+		LastStableCheckpoint: node.StableCheckpoint(),
+		PreparedProofs:       proofs,
+	}
+
+	LogStage(fmt.Sprintf("ViewChange (proposed view %d)", newView), false)
+	node.GetViewChange(msg)
+	node.Broadcast(msg, "/viewchange")
+}
+
+// GetViewChange processes an incoming (or locally generated) ViewChangeMsg,
+// and once 2f+1 have been collected for the same candidate view, has the
+// designated new primary broadcast a NewViewMsg.
+func (node *Node) GetViewChange(msg *ViewChangeMsg) error {
+	f := len(node.NodeTable) / 3
+
+	node.vc.mu.Lock()
+	bucket, ok := node.vc.votes[msg.NewViewID]
+	if !ok {
+		bucket = make(map[string]*ViewChangeMsg)
+		node.vc.votes[msg.NewViewID] = bucket
+	}
+	bucket[msg.NodeID] = msg
+	quorum := len(bucket) >= 2*f+1
+	alreadySent := node.vc.sentNewView[msg.NewViewID]
+	node.vc.mu.Unlock()
+
+	if !quorum || alreadySent {
+		return nil
+	}
+	if node.newPrimaryFor(msg.NewViewID) != node.NodeID {
+		return nil
+	}
+
+	node.vc.mu.Lock()
+	node.vc.sentNewView[msg.NewViewID] = true
+	votes := make([]*ViewChangeMsg, 0, len(bucket))
+	for _, v := range bucket {
+		votes = append(votes, v)
+	}
+	node.vc.mu.Unlock()
+
+	newView := &NewViewMsg{
+		ViewID:      msg.NewViewID,
+		NodeID:      node.NodeID,
+		ViewChanges: votes,
+		PrePrepares: node.reproposalsFromViewChanges(votes),
+	}
+
+	LogStage(fmt.Sprintf("NewView (view %d)", msg.NewViewID), false)
+	node.GetNewView(newView)
+	node.Broadcast(newView, "/newview")
+	return nil
+}
+
+// GetNewView installs the view announced by msg, provided it carries a
+// valid 2f+1 quorum of ViewChangeMsgs, then resumes consensus by handing
+// its re-proposed PrePrepares to resolveMsg, exactly as routeMsg does for
+// a freshly arrived batch of PrePrepareMsgs. GetNewView always runs on
+// dispatchMsg's own goroutine (it's reached only via routeMsg or, through
+// beginViewChange, routeMsgWhenAlarmed); MsgEntrance has that same
+// goroutine as its only reader, so sending re-proposals there would be a
+// self-deadlock. MsgDelivery is read by the separate resolveMsg goroutine,
+// so handing them off there resumes consensus without blocking dispatch.
+func (node *Node) GetNewView(msg *NewViewMsg) error {
+	f := len(node.NodeTable) / 3
+	if len(msg.ViewChanges) < 2*f+1 {
+		return fmt.Errorf("pbftserver: NewView for view %d has only %d ViewChangeMsgs, need %d", msg.ViewID, len(msg.ViewChanges), 2*f+1)
+	}
+	if node.newPrimaryFor(msg.ViewID) != msg.NodeID {
+		return fmt.Errorf("pbftserver: NewView for view %d claims primary %s, expected %s", msg.ViewID, msg.NodeID, node.newPrimaryFor(msg.ViewID))
+	}
+
+	node.vc.mu.Lock()
+	node.View = &View{ID: msg.ViewID, Primary: msg.NodeID}
+	node.CurrentState = nil
+	// Reset the escalation backoff for every sequence this NewView
+	// resumes, so the new primary gets its own backoff window instead of
+	// inheriting a clock that's been running since before this view change
+	// even started (which would otherwise make a second, immediate view
+	// change likely if the new primary is merely slow rather than faulty).
+	for _, prePrepare := range msg.PrePrepares {
+		delete(node.vc.stuckSince, prePrepare.SequenceID)
+		delete(node.vc.attempts, prePrepare.SequenceID)
+	}
+	node.vc.mu.Unlock()
+
+	LogStage(fmt.Sprintf("ViewChanged (now view %d, primary %s)", msg.ViewID, msg.NodeID), true)
+	if node.ViewChanged != nil {
+		select {
+		case node.ViewChanged <- msg.ViewID:
+		default:
+		}
+	}
+
+	if len(msg.PrePrepares) > 0 {
+		node.MsgDelivery <- msg.PrePrepares
+	}
+	return nil
+}
+
+// viewIDSentinelBase is the placeholder View.ID NewNodeWithTransport seeds
+// before any view change has happened (see node.go's viewID constant).
+// beaconRoundForView subtracts it back out so the beacon is always queried
+// with small, sequential round numbers counting up from 0 as view changes
+// actually occur, rather than the sentinel itself (a round no real beacon
+// chain will ever reach, which is why this always silently fell back to
+// round-robin).
+const viewIDSentinelBase = 10000000000
+
+func beaconRoundForView(viewID int64) uint64 {
+	if viewID >= viewIDSentinelBase {
+		return uint64(viewID - viewIDSentinelBase)
+	}
+	return uint64(viewID)
+}
+
+// beaconQueryTimeout bounds how long newPrimaryFor will wait on a slow or
+// unreachable beacon before falling back to round-robin, so a stalled
+// beacon can't wedge the single dispatch goroutine that calls this.
+const beaconQueryTimeout = 2 * time.Second
+
+// newPrimaryFor returns the NodeID that should lead viewID. When node.Beacon
+// is set, the choice is derived from that view's beacon entry so no replica
+// can predict or bias who leads an upcoming view; otherwise it falls back
+// to a round-robin order over NodeTable sorted by NodeID.
+func (node *Node) newPrimaryFor(viewID int64) string {
+	ids := make([]string, 0, len(node.NodeTable))
+	for id := range node.NodeTable {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if len(ids) == 0 {
+		return ""
+	}
+
+	if node.Beacon != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), beaconQueryTimeout)
+		entry, err := node.Beacon.Entry(ctx, beaconRoundForView(viewID))
+		cancel()
+		if err == nil {
+			digest := sha256.Sum256(entry.Randomness)
+			idx := binary.BigEndian.Uint64(digest[:8]) % uint64(len(ids))
+			return ids[idx]
+		}
+		fmt.Println("pbftserver: beacon entry for view", viewID, "unavailable, falling back to round-robin:", err)
+	}
+
+	return ids[int(viewID)%len(ids)]
+}
+
+// reproposalsFromViewChanges merges the PreparedProofs carried by votes
+// into one PrePrepareMsg per sequence, trusting only proofs that pass
+// validatePreparedProof — a genuine 2f+1 quorum of matching, validly-signed
+// prepare votes for that sequence, not just someone's say-so. Ties (more
+// than one valid proof for the same sequence, which can't happen in a
+// correct run since prepares only converge on one digest) are broken by
+// whichever was observed first.
+func (node *Node) reproposalsFromViewChanges(votes []*ViewChangeMsg) []*consensus.PrePrepareMsg {
+	f := len(node.NodeTable) / 3
+
+	bySeq := make(map[int64]*consensus.PrePrepareMsg)
+	for _, vote := range votes {
+		for _, proof := range vote.PreparedProofs {
+			if _, ok := bySeq[proof.SequenceID]; ok {
+				continue
+			}
+			if !node.validatePreparedProof(proof, f) {
+				continue
+			}
+			bySeq[proof.SequenceID] = proof.PrePrepare
+		}
+	}
+
+	seqIDs := make([]int64, 0, len(bySeq))
+	for seqID := range bySeq {
+		seqIDs = append(seqIDs, seqID)
+	}
+	sort.Slice(seqIDs, func(i, j int) bool { return seqIDs[i] < seqIDs[j] })
+
+	out := make([]*consensus.PrePrepareMsg, 0, len(seqIDs))
+	for _, seqID := range seqIDs {
+		out = append(out, bySeq[seqID])
+	}
+	return out
+}
@@ -0,0 +1,217 @@
+package network
+
+import (
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/truechain/truechain-engineering-code/common"
+	"github.com/truechain/truechain-engineering-code/crypto"
+	"github.com/truechain/truechain-engineering-code/etrue"
+	"github.com/truechain/truechain-engineering-code/pbftserver/consensus"
+)
+
+// newViewChangeTestCommittee builds a 4-member committee (f=1, quorum=3) of
+// signing keys plus a Node for the first member, wired up enough to run the
+// view-change quorum and proof-validation logic without NewNode's
+// transport/dispatch goroutines.
+func newViewChangeTestCommittee(t *testing.T) (node *Node, keys []*ecdsa.PrivateKey, ids []string) {
+	t.Helper()
+
+	nodeTable := make(map[string]string)
+	for i := 0; i < 4; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generate key %d: %v", i, err)
+		}
+		id := common.ToHex(crypto.FromECDSAPub(&key.PublicKey))
+		keys = append(keys, key)
+		ids = append(ids, id)
+		nodeTable[id] = "peer"
+	}
+
+	node = &Node{
+		NodeID:      ids[0],
+		NodeTable:   nodeTable,
+		Transport:   nopTransport{},
+		View:        &View{ID: 0, Primary: ids[0]},
+		MsgEntrance: make(chan interface{}, 8),
+		MsgDelivery: make(chan interface{}, 8),
+		ViewChanged: make(chan int64, 1),
+		vc:          newViewChangeState(),
+		cp:          newCheckpointState(nil),
+		dedup:       newDedupCache(dedupCacheSize),
+	}
+	node.SetSigningKey(keys[0])
+	return node, keys, ids
+}
+
+// nopTransport discards every broadcast, so code paths that end in
+// Node.Broadcast can run without a network.
+type nopTransport struct{}
+
+func (nopTransport) Broadcast(msg interface{}, topic string) map[string]error  { return nil }
+func (nopTransport) Subscribe(topic string, entrance chan<- interface{}) error { return nil }
+func (nopTransport) Close() error                                              { return nil }
+
+// signedPrepareEnvelope builds a validly-signed "prepare" Envelope as signer
+// would have produced it, for use as PreparedProof evidence.
+func signedPrepareEnvelope(t *testing.T, signer *ecdsa.PrivateKey, senderID string, viewID, seqID int64, digest string) *Envelope {
+	t.Helper()
+	payload, err := cbor.Marshal(&consensus.VoteMsg{ViewID: viewID, SequenceID: seqID, Digest: digest, NodeID: senderID})
+	if err != nil {
+		t.Fatalf("marshal vote: %v", err)
+	}
+	env := &Envelope{MsgType: "prepare", ViewID: viewID, SequenceID: seqID, SenderID: senderID, Payload: payload}
+	sig, err := etrue.Sign(envelopeDigest(env), signer)
+	if err != nil {
+		t.Fatalf("sign prepare envelope: %v", err)
+	}
+	env.Signature = sig
+	return env
+}
+
+func TestValidatePreparedProofAcceptsQuorum(t *testing.T) {
+	node, keys, ids := newViewChangeTestCommittee(t)
+	f := len(node.NodeTable) / 3 // f=1, quorum=3
+
+	prePrepare := &consensus.PrePrepareMsg{ViewID: 1, SequenceID: 5, Digest: "d"}
+	proof := &PreparedProof{SequenceID: 5, PrePrepare: prePrepare}
+	for i := 0; i < 3; i++ {
+		proof.Prepares = append(proof.Prepares, signedPrepareEnvelope(t, keys[i], ids[i], 1, 5, "d"))
+	}
+
+	if !node.validatePreparedProof(proof, f) {
+		t.Fatal("validatePreparedProof rejected a genuine 2f+1 quorum")
+	}
+}
+
+func TestValidatePreparedProofRejectsShortOfQuorum(t *testing.T) {
+	node, keys, ids := newViewChangeTestCommittee(t)
+	f := len(node.NodeTable) / 3
+
+	prePrepare := &consensus.PrePrepareMsg{ViewID: 1, SequenceID: 5, Digest: "d"}
+	proof := &PreparedProof{SequenceID: 5, PrePrepare: prePrepare}
+	for i := 0; i < 2; i++ { // one short of the 3 needed
+		proof.Prepares = append(proof.Prepares, signedPrepareEnvelope(t, keys[i], ids[i], 1, 5, "d"))
+	}
+
+	if node.validatePreparedProof(proof, f) {
+		t.Fatal("validatePreparedProof accepted a proof short of quorum")
+	}
+}
+
+// TestValidatePreparedProofRejectsForgedVotes is the regression test for the
+// vulnerability this check closes: a Byzantine replica cannot manufacture a
+// PreparedProof by attributing bogus votes to other committee members,
+// because those "votes" were never signed by them.
+func TestValidatePreparedProofRejectsForgedVotes(t *testing.T) {
+	node, keys, ids := newViewChangeTestCommittee(t)
+	f := len(node.NodeTable) / 3
+
+	attacker := keys[3]
+	prePrepare := &consensus.PrePrepareMsg{ViewID: 1, SequenceID: 5, Digest: "d"}
+	proof := &PreparedProof{SequenceID: 5, PrePrepare: prePrepare}
+	// The attacker signs every "vote" itself but claims each came from a
+	// different, innocent replica.
+	for i := 0; i < 3; i++ {
+		env := signedPrepareEnvelope(t, attacker, ids[i], 1, 5, "d")
+		proof.Prepares = append(proof.Prepares, env)
+	}
+
+	if node.validatePreparedProof(proof, f) {
+		t.Fatal("validatePreparedProof accepted votes forged under another replica's identity")
+	}
+}
+
+func TestValidatePreparedProofRejectsMismatchedDigest(t *testing.T) {
+	node, keys, ids := newViewChangeTestCommittee(t)
+	f := len(node.NodeTable) / 3
+
+	prePrepare := &consensus.PrePrepareMsg{ViewID: 1, SequenceID: 5, Digest: "real-digest"}
+	proof := &PreparedProof{SequenceID: 5, PrePrepare: prePrepare}
+	for i := 0; i < 3; i++ {
+		proof.Prepares = append(proof.Prepares, signedPrepareEnvelope(t, keys[i], ids[i], 1, 5, "other-digest"))
+	}
+
+	if node.validatePreparedProof(proof, f) {
+		t.Fatal("validatePreparedProof accepted votes for a different digest than the PrePrepare")
+	}
+}
+
+func TestReproposalsFromViewChangesDropsUnvalidatedProofs(t *testing.T) {
+	node, keys, ids := newViewChangeTestCommittee(t)
+
+	goodPrePrepare := &consensus.PrePrepareMsg{ViewID: 1, SequenceID: 1, Digest: "d1"}
+	goodProof := &PreparedProof{SequenceID: 1, PrePrepare: goodPrePrepare}
+	for i := 0; i < 3; i++ {
+		goodProof.Prepares = append(goodProof.Prepares, signedPrepareEnvelope(t, keys[i], ids[i], 1, 1, "d1"))
+	}
+
+	forgedPrePrepare := &consensus.PrePrepareMsg{ViewID: 1, SequenceID: 2, Digest: "d2"}
+	forgedProof := &PreparedProof{SequenceID: 2, PrePrepare: forgedPrePrepare}
+	forgedProof.Prepares = append(forgedProof.Prepares, signedPrepareEnvelope(t, keys[3], ids[3], 1, 2, "d2"))
+
+	votes := []*ViewChangeMsg{{NewViewID: 1, NodeID: ids[0], PreparedProofs: []*PreparedProof{goodProof, forgedProof}}}
+	out := node.reproposalsFromViewChanges(votes)
+
+	if len(out) != 1 || out[0].SequenceID != 1 {
+		t.Fatalf("reproposalsFromViewChanges = %v, want only sequence 1's PrePrepare", out)
+	}
+}
+
+func TestGetViewChangeFormsQuorumAndElectsPrimary(t *testing.T) {
+	node, _, ids := newViewChangeTestCommittee(t)
+
+	// Make this replica the view's elected primary so GetViewChange, once
+	// quorum forms, proceeds to broadcast a NewView instead of returning early.
+	node.NodeID = node.newPrimaryFor(1)
+
+	for _, id := range ids {
+		if err := node.GetViewChange(&ViewChangeMsg{NewViewID: 1, NodeID: id}); err != nil {
+			t.Fatalf("GetViewChange(%s): %v", id, err)
+		}
+	}
+
+	select {
+	case gotView := <-node.ViewChanged:
+		if gotView != 1 {
+			t.Fatalf("ViewChanged = %d, want 1", gotView)
+		}
+	default:
+		t.Fatal("GetViewChange did not install the new view after reaching quorum")
+	}
+}
+
+func TestGetNewViewResetsBackoffForResumedSequences(t *testing.T) {
+	node, _, ids := newViewChangeTestCommittee(t)
+	node.vc.stuckSince[42] = time.Now()
+	node.vc.attempts[42] = 3
+
+	quorum := 2*(len(node.NodeTable)/3) + 1
+	viewChanges := make([]*ViewChangeMsg, quorum)
+	for i := range viewChanges {
+		viewChanges[i] = &ViewChangeMsg{NewViewID: 1, NodeID: ids[i%len(ids)]}
+	}
+
+	msg := &NewViewMsg{
+		ViewID:      1,
+		NodeID:      node.newPrimaryFor(1),
+		ViewChanges: viewChanges,
+		PrePrepares: []*consensus.PrePrepareMsg{{ViewID: 1, SequenceID: 42, Digest: "d"}},
+	}
+
+	if err := node.GetNewView(msg); err != nil {
+		t.Fatalf("GetNewView: %v", err)
+	}
+	<-node.MsgDelivery // GetNewView hands msg.PrePrepares to resolveMsg via MsgDelivery
+
+	node.vc.mu.Lock()
+	_, stillStuck := node.vc.stuckSince[42]
+	_, stillAttempted := node.vc.attempts[42]
+	node.vc.mu.Unlock()
+	if stillStuck || stillAttempted {
+		t.Fatal("GetNewView did not reset the escalation backoff for a resumed sequence")
+	}
+}